@@ -0,0 +1,10 @@
+package config
+
+// JwtKeyConf jwt 签名密钥配置，HMAC 使用 Secret，非对称算法使用 PEM 格式的 Key 或 KeyFile
+type JwtKeyConf struct {
+	Kid       string `json:",optional"`                                                                                         // 密钥 ID，写入令牌头部的 kid
+	Algorithm string `json:",optional,options=[HS256,HS384,HS512,RS256,RS384,RS512,PS256,PS384,PS512,ES256,ES384,ES512,EdDSA]"` // 为空时根据密钥类型推断
+	Secret    string `json:",optional"`                                                                                         // HMAC 共享密钥
+	Key       string `json:",optional"`                                                                                         // PEM 内容，私钥可签发令牌，公钥仅能校验
+	KeyFile   string `json:",optional"`                                                                                         // PEM 文件路径，与 Key 二选一
+}
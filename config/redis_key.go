@@ -1,3 +1,10 @@
 package config
 
-const RedisCaptchaPrefix = "CAPTCHA:"
+const (
+	RedisCaptchaPrefix    = "CAPTCHA:"
+	RedisJwtRefreshPrefix = "JWT:REFRESH:"
+	RedisJwtRevokePrefix  = "JWT:REVOKE:"
+	RedisRbacPrefix       = "RBAC:ROLE:"
+	RedisUploadPrefix     = "UPLOAD:SESSION:"
+	RedisUploadHashPrefix = "UPLOAD:HASH:"
+)
@@ -1,12 +1,18 @@
 package config
 
+import "time"
+
 var GlobalStorage StorageConf
 
 type StorageConf struct {
-	Driver string `json:",default=local,options=[local,oss]"` // local/oss
-	Local  struct {
-		Directory string `json:",default=storage"`
-		BaseUrl   string `json:",optional"`
+	Driver  string `json:",default=local"` // local/oss/s3/cos/qiniu 或通过 upload.Register 注册的驱动
+	CdnURL  string `json:",optional"`      // CDN 加速域名，设置后 URL 生成的地址使用该域名
+	Private bool   `json:",optional"`      // 存储桶为私有读时，URL 默认生成带签名的地址
+	Local   struct {
+		Directory  string `json:",default=storage"`
+		BaseUrl    string `json:",optional"`
+		SigningKey string `json:",optional"`                        // 预签名地址使用的密钥，为空时不支持预签名
+		Session    string `json:",default=file,options=file|redis"` // 分片上传会话的存储方式，redis 使用 define.GlobalRedis
 	} `json:",optional"`
 	Oss struct {
 		Endpoint        string `json:",optional"`
@@ -15,4 +21,44 @@ type StorageConf struct {
 		BucketName      string `json:",optional"`
 		BucketURL       string `json:",optional"`
 	} `json:",optional"`
+	S3 struct {
+		Endpoint        string `json:",optional"`          // 为空时使用 AWS 地址 https://s3.<Region>.amazonaws.com，MinIO 填写如 http://127.0.0.1:9000
+		Region          string `json:",default=us-east-1"` // 签名使用的区域，MinIO 保持默认即可
+		AccessKeyID     string `json:",optional"`          // 访问密钥 ID
+		SecretAccessKey string `json:",optional"`          // 访问密钥
+		BucketName      string `json:",optional"`          // 存储桶名称
+		BucketURL       string `json:",optional"`          // 存储桶公开访问地址
+		UsePathStyle    bool   `json:",optional"`          // 使用路径风格地址 <Endpoint>/<BucketName>，MinIO 通常需要开启
+	} `json:",optional"`
+	Cos struct {
+		BucketURL string `json:",optional"` // 存储桶访问地址，如 https://examplebucket-1250000000.cos.ap-guangzhou.myqcloud.com
+		SecretID  string `json:",optional"` // 访问密钥 SecretId
+		SecretKey string `json:",optional"` // 访问密钥 SecretKey
+	} `json:",optional"`
+	Qiniu struct {
+		AccessKey  string `json:",optional"`
+		SecretKey  string `json:",optional"`
+		BucketName string `json:",optional"`
+		BucketURL  string `json:",optional"`                         // 存储桶绑定的访问域名
+		UpHost     string `json:",default=https://up.qiniup.com"`    // 上传地址，按存储区域填写
+		RsHost     string `json:",default=https://rs.qiniuapi.com"`  // 资源管理地址
+		RsfHost    string `json:",default=https://rsf.qiniuapi.com"` // 资源列举地址
+	} `json:",optional"`
+	Janitor JanitorConf       `json:",optional"`
+	Policy  UploadPolicyConf  `json:",optional"`
+	Extra   map[string]string `json:",optional"` // 第三方驱动的自定义配置
+}
+
+// JanitorConf 清理废弃分片上传的配置
+type JanitorConf struct {
+	MaxAge   time.Duration `json:",default=24h"` // 分片上传超过该时长没有新的分片即视为废弃
+	Interval time.Duration `json:",default=1h"`  // 清理间隔
+	DryRun   bool          `json:",optional"`    // 只生成报告，不删除
+}
+
+// UploadPolicyConf 上传校验策略，字段为空时不限制
+type UploadPolicyConf struct {
+	MaxSize      int64    `json:",optional"` // 单个文件的最大字节数
+	AllowedExts  []string `json:",optional"` // 允许的扩展名，如 .jpg、.mp4，不区分大小写
+	AllowedMimes []string `json:",optional"` // 允许的 MIME 类型，支持 image/* 形式的通配
 }
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 令牌中约定的自定义声明名称
+const (
+	ClaimUserId   = "userId"
+	ClaimRoleId   = "roleId"
+	ClaimTenantId = "tenantId"
+)
+
+type claimsKey struct{}
+
+// Claims 认证通过后注入请求上下文的用户信息
+type Claims struct {
+	UserId   string
+	RoleId   int64
+	TenantId string
+	Raw      jwt.MapClaims // 令牌中的全部声明
+}
+
+// NewClaims 从令牌声明中提取用户信息，数字与字符串形式的 ID 都可以识别
+func NewClaims(raw jwt.MapClaims) *Claims {
+	return &Claims{
+		UserId:   toString(raw[ClaimUserId]),
+		RoleId:   toInt64(raw[ClaimRoleId]),
+		TenantId: toString(raw[ClaimTenantId]),
+		Raw:      raw,
+	}
+}
+
+// WithClaims 将用户信息写入上下文
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext 从上下文中读取用户信息
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// UserIdFromContext 从上下文中读取用户 ID，未认证时返回空字符串
+func UserIdFromContext(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.UserId
+	}
+	return ""
+}
+
+// RoleIdFromContext 从上下文中读取角色 ID，未认证时返回 0
+func RoleIdFromContext(ctx context.Context) int64 {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.RoleId
+	}
+	return 0
+}
+
+// TenantIdFromContext 从上下文中读取租户 ID，未认证时返回空字符串
+func TenantIdFromContext(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.TenantId
+	}
+	return ""
+}
+
+func toString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return val.String()
+	default:
+		return ""
+	}
+}
+
+func toInt64(v any) int64 {
+	switch val := v.(type) {
+	case float64:
+		return int64(val)
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		f, _ := val.Float64()
+		return int64(f)
+	case string:
+		n, _ := strconv.ParseInt(val, 10, 64)
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+	jwtx "github.com/zhanghaidi/zero-common/utils/jwt"
+	"github.com/zhanghaidi/zero-common/utils/response"
+)
+
+func TestJwtMiddleware(t *testing.T) {
+	secretKey := "jS6VKDtsJf3z1n2VKDtsJf3z1n2"
+	now := time.Now().Unix()
+	valid, _ := jwtx.NewJwtToken(secretKey, now, 60,
+		jwtx.WithOption(ClaimUserId, int64(1234567890123456789)), jwtx.WithOption(ClaimRoleId, 2), jwtx.WithOption(ClaimTenantId, "t1"))
+	expired, _ := jwtx.NewJwtToken(secretKey, now-120, 60)
+	forged, _ := jwtx.NewJwtToken("another-secret", now, 60)
+
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+	}{
+		{name: "valid", header: "Bearer " + valid, wantCode: 0},
+		{name: "missing", header: "", wantCode: jwtx.CodeTokenMissing},
+		{name: "wrong scheme", header: "Basic " + valid, wantCode: jwtx.CodeTokenMissing},
+		{name: "expired", header: "Bearer " + expired, wantCode: jwtx.CodeTokenExpired},
+		{name: "bad signature", header: "bearer " + forged, wantCode: jwtx.CodeTokenSignatureInvalid},
+		{name: "malformed", header: "Bearer abc", wantCode: jwtx.CodeTokenMalformed},
+	}
+
+	m := NewJwtMiddleware(jwtx.NewHMACKey("", secretKey))
+	handler := m.Handle(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok || claims.UserId != "1234567890123456789" || claims.RoleId != 2 || claims.TenantId != "t1" {
+			t.Errorf("claims = %+v", claims)
+		}
+		response.Response(w, nil, nil)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			var body response.Body
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", body.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestJwtMiddlewareRevocationError(t *testing.T) {
+	secretKey := "jS6VKDtsJf3z1n2VKDtsJf3z1n2"
+	token, _ := jwtx.NewJwtToken(secretKey, time.Now().Unix(), 60, jwtx.WithOption(ClaimUserId, "abc"), jwtx.WithJti())
+
+	mr := miniredis.RunT(t)
+	store := jwtx.NewRevocationStore(redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1}), time.Hour)
+	mr.Close()
+
+	m := NewJwtMiddleware(jwtx.NewHMACKey("", secretKey), WithRevocationStore(store))
+	handler := m.Handle(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called when revocation check failed")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	// Redis 错误不能透传给客户端
+	var body response.Body
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+	}
+	if body.Code != errorx.DefaultCode || body.Msg != ErrAuthUnavailable.Error() {
+		t.Errorf("body = %+v", body)
+	}
+}
@@ -0,0 +1,24 @@
+package jwt
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// NewToken 签发类型化声明的令牌，T 通常内嵌 jwt.RegisteredClaims 并声明自定义字段，
+// 与 Option 方式签发的令牌格式一致，两种方式可以互相解析
+func NewToken[T jwt.Claims](signer Signer, claims T) (string, error) {
+	return signer.Sign(claims)
+}
+
+// ParseToken 校验令牌并将声明解析到类型 T 中，如 ParseToken[UserClaims](keys, token)
+func ParseToken[T any, PT interface {
+	*T
+	jwt.Claims
+}](keys KeySet, tokenString string, opts ...ParseOption) (*T, error) {
+	claims := PT(new(T))
+	if err := parseToken(tokenString, claims, keySetFunc(keys), nil, opts); err != nil {
+		return nil, err
+	}
+
+	return (*T)(claims), nil
+}
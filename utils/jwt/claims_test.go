@@ -0,0 +1,55 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type userClaims struct {
+	UserId string `json:"userId"`
+	RoleId int64  `json:"roleId"`
+	jwt.RegisteredClaims
+}
+
+func TestNewTokenAndParseToken(t *testing.T) {
+	key := NewHMACKey("", "jS6VKDtsJf3z1n2VKDtsJf3z1n2")
+	now := time.Now()
+
+	token, err := NewToken(key, userClaims{
+		UserId: "abc",
+		RoleId: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+
+	claims, err := ParseToken[userClaims](key, token)
+	if err != nil {
+		t.Fatalf("ParseToken() error = %v", err)
+	}
+	if claims.UserId != "abc" || claims.RoleId != 1 {
+		t.Errorf("ParseToken() claims = %+v", claims)
+	}
+
+	// Option 方式签发的令牌同样可以解析为类型化声明
+	legacy, _ := NewJwtToken("jS6VKDtsJf3z1n2VKDtsJf3z1n2", now.Unix(), 60,
+		WithOption("userId", "abc"), WithOption("roleId", 2))
+	claims, err = ParseToken[userClaims](key, legacy)
+	if err != nil {
+		t.Fatalf("ParseToken() legacy error = %v", err)
+	}
+	if claims.UserId != "abc" || claims.RoleId != 2 || claims.ExpiresAt.Unix() != now.Unix()+60 {
+		t.Errorf("ParseToken() legacy claims = %+v", claims)
+	}
+
+	if _, err = ParseToken[userClaims](NewHMACKey("", "another-secret"), token); !errors.Is(err, ErrTokenSignatureInvalid) {
+		t.Errorf("ParseToken() error = %v, want %v", err, ErrTokenSignatureInvalid)
+	}
+}
@@ -0,0 +1,45 @@
+package jwt
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+)
+
+// 令牌相关错误码，取值与 HTTP 401 对应，便于客户端统一处理重新登录
+const (
+	CodeTokenInvalid          = 40100
+	CodeTokenMalformed        = 40101
+	CodeTokenExpired          = 40102
+	CodeTokenSignatureInvalid = 40103
+	CodeRefreshTokenInvalid   = 40104
+	CodeRefreshTokenReused    = 40105
+	CodeTokenRevoked          = 40106
+	CodeTokenMissing          = 40107
+)
+
+var (
+	ErrTokenInvalid          = errorx.NewCodeError(CodeTokenInvalid, "token无效")
+	ErrTokenMalformed        = errorx.NewCodeError(CodeTokenMalformed, "token格式错误")
+	ErrTokenExpired          = errorx.NewCodeError(CodeTokenExpired, "token已过期")
+	ErrTokenSignatureInvalid = errorx.NewCodeError(CodeTokenSignatureInvalid, "token签名无效")
+	ErrRefreshTokenInvalid   = errorx.NewCodeError(CodeRefreshTokenInvalid, "刷新token无效或已过期")
+	ErrRefreshTokenReused    = errorx.NewCodeError(CodeRefreshTokenReused, "刷新token已被使用，请重新登录")
+	ErrTokenRevoked          = errorx.NewCodeError(CodeTokenRevoked, "token已失效，请重新登录")
+	ErrTokenMissing          = errorx.NewCodeError(CodeTokenMissing, "未登录或缺少token")
+)
+
+// convertError 将 golang-jwt 的校验错误转换为 errorx.CodeError
+func convertError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenSignatureInvalid
+	default:
+		return ErrTokenInvalid
+	}
+}
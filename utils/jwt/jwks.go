@@ -0,0 +1,342 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/syncx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+const (
+	defaultJWKSRefreshInterval = 10 * time.Minute
+	defaultJWKSRefreshLimit    = 30 * time.Second
+	defaultJWKSMaxAge          = 5 * time.Minute
+)
+
+// JWK 描述 RFC 7517 中的单个公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS 描述 JWK Set 文档
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK 将公钥导出为 JWK，HMAC 密钥不能公开导出
+func (k *Key) JWK() (JWK, error) {
+	jwk := JWK{
+		Kid: k.ID,
+		Use: "sig",
+	}
+	if len(k.algs) == 0 {
+		jwk.Alg = k.Method.Alg()
+	}
+
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeSegment(pub.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = encodeSegment(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeSegment(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeSegment(pub)
+	default:
+		return JWK{}, fmt.Errorf("jwt: key %q has no public part to export", k.ID)
+	}
+
+	return jwk, nil
+}
+
+// Key 将 JWK 转换为仅可校验的 Key，未声明 alg 时允许 kty 对应的全部算法，EC 公钥必须位于声明的曲线上
+func (j JWK) Key() (*Key, error) {
+	var pub any
+
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeSegment(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(j.E)
+		if err != nil {
+			return nil, err
+		}
+		pub = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("jwt: unsupported curve %q", j.Crv)
+		}
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, errors.New("jwt: invalid EC public key")
+		}
+		ec := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		// 转换为 ECDH 公钥时会校验点是否在曲线上，拒绝无效曲线攻击使用的公钥
+		if _, err = ec.ECDH(); err != nil {
+			return nil, fmt.Errorf("jwt: invalid EC public key: %w", err)
+		}
+		pub = ec
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("jwt: unsupported curve %q", j.Crv)
+		}
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("jwt: invalid Ed25519 public key")
+		}
+		pub = ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("jwt: unsupported key type %q", j.Kty)
+	}
+
+	k, err := NewKey(j.Kid, j.Alg, pub)
+	if err != nil {
+		return nil, err
+	}
+	// 未声明 alg 时允许该密钥类型支持的全部算法，如 RSA 密钥可以校验 RS256 与 PS256
+	if j.Alg == "" {
+		k.algs = keyAlgorithms(pub)
+	}
+	return k, nil
+}
+
+// KeyLister 列出需要发布的密钥，KeyRing 实现了该接口
+type KeyLister interface {
+	Keys() []*Key
+}
+
+// JWKSHandler 返回以 JWKS 文档发布公钥的 http.Handler，HMAC 密钥会被忽略
+func JWKSHandler(keys KeyLister) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set := JWKS{Keys: []JWK{}}
+		for _, k := range keys.Keys() {
+			jwk, err := k.JWK()
+			if err != nil {
+				continue
+			}
+			set.Keys = append(set.Keys, jwk)
+		}
+
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(defaultJWKSMaxAge.Seconds())))
+		httpx.OkJson(w, set)
+	})
+}
+
+// RemoteKeySetOption 定义 RemoteKeySet 的可选配置
+type RemoteKeySetOption func(*RemoteKeySet)
+
+// WithHTTPClient 设置拉取 JWKS 使用的 http.Client
+func WithHTTPClient(client *http.Client) RemoteKeySetOption {
+	return func(s *RemoteKeySet) {
+		s.client = client
+	}
+}
+
+// WithRefreshInterval 设置缓存的刷新周期
+func WithRefreshInterval(interval time.Duration) RemoteKeySetOption {
+	return func(s *RemoteKeySet) {
+		s.refreshInterval = interval
+	}
+}
+
+// WithRefreshLimit 设置遇到未知 kid 时两次强制刷新之间的最小间隔，防止伪造 kid 打满签发方
+func WithRefreshLimit(limit time.Duration) RemoteKeySetOption {
+	return func(s *RemoteKeySet) {
+		s.refreshLimit = limit
+	}
+}
+
+// RemoteKeySet 从 JWKS 地址拉取并缓存公钥，缓存过期或遇到未知 kid 时自动刷新
+type RemoteKeySet struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	refreshLimit    time.Duration
+	flight          syncx.SingleFlight
+
+	mu        sync.RWMutex
+	keys      map[string]*Key
+	fetchedAt time.Time
+}
+
+// NewRemoteKeySet 创建从 url 拉取 JWKS 的 KeySet，首次校验时才会发起请求
+func NewRemoteKeySet(url string, opts ...RemoteKeySetOption) *RemoteKeySet {
+	s := &RemoteKeySet{
+		url:             url,
+		client:          &http.Client{Timeout: 5 * time.Second},
+		refreshInterval: defaultJWKSRefreshInterval,
+		refreshLimit:    defaultJWKSRefreshLimit,
+		flight:          syncx.NewSingleFlight(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// VerificationKey 实现 KeySet
+func (s *RemoteKeySet) VerificationKey(kid, alg string) (any, error) {
+	s.mu.RLock()
+	fetchedAt := s.fetchedAt
+	s.mu.RUnlock()
+
+	if time.Since(fetchedAt) > s.refreshInterval {
+		s.refreshQuietly()
+	}
+
+	if key, err := s.lookup(kid, alg); !errors.Is(err, ErrKeyNotFound) {
+		return key, err
+	}
+
+	// 未知 kid 可能是签发方刚轮换了密钥，限频后强制刷新一次
+	s.mu.RLock()
+	fetchedAt = s.fetchedAt
+	s.mu.RUnlock()
+	if time.Since(fetchedAt) < s.refreshLimit {
+		return nil, ErrKeyNotFound
+	}
+	s.refreshQuietly()
+
+	return s.lookup(kid, alg)
+}
+
+// Refresh 立即从远端拉取 JWKS 并替换缓存
+func (s *RemoteKeySet) Refresh(ctx context.Context) error {
+	_, err := s.flight.Do(s.url, func() (any, error) {
+		return nil, s.fetch(ctx)
+	})
+	return err
+}
+
+func (s *RemoteKeySet) refreshQuietly() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.client.Timeout+time.Second)
+	defer cancel()
+
+	// 刷新失败时继续使用已缓存的公钥
+	if err := s.Refresh(ctx); err != nil {
+		logx.Errorw("failed to refresh jwks", logx.Field("url", s.url), logx.Field("detail", err))
+	}
+}
+
+func (s *RemoteKeySet) lookup(kid, alg string) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return k.VerificationKey(kid, alg)
+}
+
+func (s *RemoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.touch()
+		return fmt.Errorf("jwt: failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		s.touch()
+		return fmt.Errorf("jwt: failed to fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		s.touch()
+		return fmt.Errorf("jwt: failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*Key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// 跳过不支持的密钥，避免单个密钥导致整个 JWKS 不可用
+		k, err := jwk.Key()
+		if err != nil {
+			logx.Errorw("skip unsupported jwk", logx.Field("kid", jwk.Kid), logx.Field("detail", err))
+			continue
+		}
+		keys[k.ID] = k
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// touch 拉取失败时也更新时间，避免在签发方故障期间每个请求都重试
+func (s *RemoteKeySet) touch() {
+	s.mu.Lock()
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: invalid base64url value: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,139 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeyRingAndRemoteKeySet(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	first, _ := NewKey("2024-01", "", rsaKey)
+	second, _ := NewKey("2024-02", "", ecKey)
+	third, _ := NewKey("2024-03", "", edKey)
+
+	ring, err := NewKeyRing(first)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+
+	srv := httptest.NewServer(JWKSHandler(ring))
+	defer srv.Close()
+
+	remote := NewRemoteKeySet(srv.URL, WithRefreshLimit(0))
+	iat := time.Now().Unix()
+
+	oldToken, err := NewJwtTokenWithSigner(ring, iat, 60, WithOption("userId", "abc"))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if err = ring.Rotate(second); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	newToken, _ := NewJwtTokenWithSigner(ring, iat, 60)
+
+	// 轮换后旧令牌仍可通过本地密钥环和远端 JWKS 校验
+	for _, token := range []string{oldToken, newToken} {
+		if err = VerifyJwtTokenWithKeySet(ring, token); err != nil {
+			t.Errorf("KeyRing verify error = %v", err)
+		}
+		if err = VerifyJwtTokenWithKeySet(remote, token); err != nil {
+			t.Errorf("RemoteKeySet verify error = %v", err)
+		}
+	}
+
+	// 未知 kid 触发远端刷新
+	_ = ring.Rotate(third)
+	latestToken, _ := NewJwtTokenWithSigner(ring, iat, 60)
+	if err = VerifyJwtTokenWithKeySet(remote, latestToken); err != nil {
+		t.Errorf("RemoteKeySet verify after rotation error = %v", err)
+	}
+
+	// 移除历史密钥后其签发的令牌失效
+	if err = ring.Remove(first.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err = ring.Remove(third.ID); err == nil {
+		t.Error("Remove() active key should fail")
+	}
+	if err = VerifyJwtTokenWithKeySet(ring, oldToken); !errors.Is(err, ErrTokenSignatureInvalid) {
+		t.Errorf("KeyRing verify removed key error = %v", err)
+	}
+
+	keys := ring.Keys()
+	if len(keys) != 2 || keys[0].ID != third.ID || keys[1].ID != second.ID {
+		t.Errorf("Keys() = %v", keys)
+	}
+}
+
+func TestJWKSHandlerSkipsHMAC(t *testing.T) {
+	ring, _ := NewKeyRing(NewHMACKey("hmac", "secret"))
+
+	srv := httptest.NewServer(JWKSHandler(ring))
+	defer srv.Close()
+
+	remote := NewRemoteKeySet(srv.URL)
+	if _, err := remote.VerificationKey("hmac", "HS256"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("VerificationKey() error = %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestJWKKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	signer, _ := NewKey("pss", "PS256", rsaKey)
+	exported, err := signer.JWK()
+	if err != nil {
+		t.Fatalf("JWK() error = %v", err)
+	}
+
+	// 未声明 alg 的 RSA 公钥可以校验 PS256 签名，但不能用于 HMAC
+	exported.Alg = ""
+	key, err := exported.Key()
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+	token, _ := NewJwtTokenWithSigner(signer, time.Now().Unix(), 60)
+	if err = VerifyJwtTokenWithKeySet(key, token); err != nil {
+		t.Errorf("verify PS256 with JWK without alg error = %v", err)
+	}
+	if _, err = key.VerificationKey("pss", "HS256"); !errors.Is(err, ErrAlgorithmInvalid) {
+		t.Errorf("VerificationKey(HS256) error = %v, want %v", err, ErrAlgorithmInvalid)
+	}
+
+	// 声明了 alg 时只允许该算法
+	exported.Alg = "RS256"
+	if key, err = exported.Key(); err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+	if err = VerifyJwtTokenWithKeySet(key, token); err == nil {
+		t.Error("verify PS256 with RS256 JWK should fail")
+	}
+
+	// 不在曲线上的 EC 公钥
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecSigner, _ := NewKey("ec", "", ecKey)
+	ecJWK, _ := ecSigner.JWK()
+	if _, err = ecJWK.Key(); err != nil {
+		t.Fatalf("Key() valid EC error = %v", err)
+	}
+	invalid := ecJWK
+	invalid.Y = encodeSegment(new(big.Int).Add(ecKey.Y, big.NewInt(1)).Bytes())
+	if _, err = invalid.Key(); err == nil {
+		t.Error("Key() with point off the curve should fail")
+	}
+	invalid.Y = encodeSegment(make([]byte, 64))
+	if _, err = invalid.Key(); err == nil {
+		t.Error("Key() with oversized coordinate should fail")
+	}
+}
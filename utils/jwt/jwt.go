@@ -1,6 +1,11 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/json"
+	"strconv"
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -18,8 +23,24 @@ func WithOption(key string, val any) Option {
 	}
 }
 
+// WithJti returns the option that stamps a random jti, which is required to revoke a single token
+func WithJti() Option {
+	return WithOption("jti", rand.Text())
+}
+
+// WithIssuedAt returns the option that stamps iat with millisecond precision,
+// so RevocationStore.RevokeUser can tell tokens issued within the same second apart
+func WithIssuedAt(t time.Time) Option {
+	return WithOption("iat", json.Number(strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)))
+}
+
 // NewJwtToken returns the jwt token from the given data.
 func NewJwtToken(secretKey string, iat, seconds int64, opt ...Option) (string, error) {
+	return NewJwtTokenWithSigner(NewHMACKey("", secretKey), iat, seconds, opt...)
+}
+
+// NewJwtTokenWithSigner returns the jwt token signed by the given signer.
+func NewJwtTokenWithSigner(signer Signer, iat, seconds int64, opt ...Option) (string, error) {
 	claims := make(jwt.MapClaims)
 	claims["exp"] = iat + seconds
 	claims["iat"] = iat
@@ -28,7 +49,5 @@ func NewJwtToken(secretKey string, iat, seconds int64, opt ...Option) (string, e
 		claims[v.Key] = v.Val
 	}
 
-	token := jwt.New(jwt.SigningMethodHS256)
-	token.Claims = claims
-	return token.SignedString([]byte(secretKey))
+	return signer.Sign(claims)
 }
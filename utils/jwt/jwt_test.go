@@ -1,6 +1,11 @@
 package jwt
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
 
 func TestNewJwtToken(t *testing.T) {
 	type args struct {
@@ -44,3 +49,45 @@ func TestNewJwtToken(t *testing.T) {
 		})
 	}
 }
+
+func TestParseJwtToken(t *testing.T) {
+	secretKey := "jS6VKDtsJf3z1n2VKDtsJf3z1n2"
+	token, err := NewJwtToken(secretKey, 1000, 10, WithOption("userId", "abc"), WithOption("roleId", 1))
+	if err != nil {
+		t.Fatalf("NewJwtToken() error = %v", err)
+	}
+
+	at := func(sec int64) ParseOption {
+		return WithTimeFunc(func() time.Time { return time.Unix(sec, 0) })
+	}
+
+	tests := []struct {
+		name      string
+		secretKey string
+		token     string
+		opts      []ParseOption
+		wantErr   error
+	}{
+		{name: "valid", secretKey: secretKey, token: token, opts: []ParseOption{at(1005)}},
+		{name: "expired", secretKey: secretKey, token: token, opts: []ParseOption{at(1011)}, wantErr: ErrTokenExpired},
+		{name: "expired within leeway", secretKey: secretKey, token: token, opts: []ParseOption{at(1011), WithLeeway(5 * time.Second)}},
+		{name: "issued in future", secretKey: secretKey, token: token, opts: []ParseOption{at(990)}, wantErr: ErrTokenInvalid},
+		{name: "bad signature", secretKey: "another-secret", token: token, opts: []ParseOption{at(1005)}, wantErr: ErrTokenSignatureInvalid},
+		{name: "malformed", secretKey: secretKey, token: "not-a-token", wantErr: ErrTokenMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseJwtToken(tt.secretKey, tt.token, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseJwtToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if claims["userId"] != "abc" || claims["roleId"] != json.Number("1") {
+				t.Errorf("ParseJwtToken() claims = %v", claims)
+			}
+		})
+	}
+}
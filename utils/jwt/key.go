@@ -0,0 +1,244 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+var (
+	ErrKeyNotFound      = errors.New("jwt: verification key not found")
+	ErrKeyCannotSign    = errors.New("jwt: key has no private part and cannot sign")
+	ErrAlgorithmInvalid = errors.New("jwt: algorithm does not match key")
+)
+
+// Signer 签发令牌
+type Signer interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// KeySet 根据令牌头部的 kid 与 alg 查找验签密钥
+type KeySet interface {
+	VerificationKey(kid, alg string) (any, error)
+}
+
+// Key 描述一个签名密钥，持有私钥时可签发令牌，仅持有公钥时只能校验令牌
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+	algs      []string // 允许校验的算法，为空时只允许 Method
+}
+
+// NewKey 根据密钥创建 Key，key 可以是 HMAC 的 []byte、RSA/ECDSA/Ed25519 私钥或公钥，alg 为空时根据密钥类型推断
+func NewKey(id, alg string, key any) (*Key, error) {
+	k := &Key{ID: id}
+
+	switch v := key.(type) {
+	case []byte:
+		k.signKey, k.verifyKey = v, v
+	case *rsa.PrivateKey:
+		k.signKey, k.verifyKey = v, &v.PublicKey
+	case *rsa.PublicKey:
+		k.verifyKey = v
+	case *ecdsa.PrivateKey:
+		k.signKey, k.verifyKey = v, &v.PublicKey
+	case *ecdsa.PublicKey:
+		k.verifyKey = v
+	case ed25519.PrivateKey:
+		k.signKey, k.verifyKey = v, v.Public()
+	case ed25519.PublicKey:
+		k.verifyKey = v
+	default:
+		return nil, fmt.Errorf("jwt: unsupported key type %T", key)
+	}
+
+	if alg == "" {
+		alg = defaultAlgorithm(k.verifyKey)
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || !methodMatchesKey(method, k.verifyKey) {
+		return nil, fmt.Errorf("%w: %s with %T", ErrAlgorithmInvalid, alg, k.verifyKey)
+	}
+	k.Method = method
+
+	return k, nil
+}
+
+// NewHMACKey 创建 HS256 共享密钥
+func NewHMACKey(id, secret string) *Key {
+	return &Key{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// ParseKeyPEM 解析 PEM 格式的私钥、公钥或证书
+func ParseKeyPEM(id, alg string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: invalid PEM data")
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("jwt: unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to parse %s: %w", block.Type, err)
+	}
+
+	return NewKey(id, alg, key)
+}
+
+// LoadKeyFile 从 PEM 文件加载密钥
+func LoadKeyFile(id, alg, filename string) (*Key, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to read key file: %w", err)
+	}
+
+	return ParseKeyPEM(id, alg, data)
+}
+
+// NewKeyFromConf 根据配置创建密钥
+func NewKeyFromConf(c config.JwtKeyConf) (*Key, error) {
+	switch {
+	case c.Secret != "":
+		alg := c.Algorithm
+		if alg == "" {
+			alg = jwt.SigningMethodHS256.Alg()
+		}
+		return NewKey(c.Kid, alg, []byte(c.Secret))
+	case c.Key != "":
+		return ParseKeyPEM(c.Kid, c.Algorithm, []byte(c.Key))
+	case c.KeyFile != "":
+		return LoadKeyFile(c.Kid, c.Algorithm, c.KeyFile)
+	default:
+		return nil, errors.New("jwt: one of Secret, Key or KeyFile is required")
+	}
+}
+
+// CanSign 是否持有私钥
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Public 返回公钥，HMAC 密钥返回 nil
+func (k *Key) Public() crypto.PublicKey {
+	if _, ok := k.verifyKey.([]byte); ok {
+		return nil
+	}
+	return k.verifyKey
+}
+
+// Sign 签发令牌，设置了 ID 时写入头部的 kid
+func (k *Key) Sign(claims jwt.Claims) (string, error) {
+	if !k.CanSign() {
+		return "", ErrKeyCannotSign
+	}
+
+	token := jwt.NewWithClaims(k.Method, claims)
+	if k.ID != "" {
+		token.Header["kid"] = k.ID
+	}
+	return token.SignedString(k.signKey)
+}
+
+// VerificationKey 实现 KeySet，kid 与 alg 均需匹配
+func (k *Key) VerificationKey(kid, alg string) (any, error) {
+	if kid != "" && k.ID != "" && kid != k.ID {
+		return nil, ErrKeyNotFound
+	}
+	if alg != k.Method.Alg() && !slices.Contains(k.algs, alg) {
+		return nil, ErrAlgorithmInvalid
+	}
+	return k.verifyKey, nil
+}
+
+func defaultAlgorithm(pub any) string {
+	switch v := pub.(type) {
+	case []byte:
+		return jwt.SigningMethodHS256.Alg()
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256.Alg()
+	case *ecdsa.PublicKey:
+		switch v.Curve.Params().BitSize {
+		case 384:
+			return jwt.SigningMethodES384.Alg()
+		case 521:
+			return jwt.SigningMethodES512.Alg()
+		default:
+			return jwt.SigningMethodES256.Alg()
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA.Alg()
+	default:
+		return ""
+	}
+}
+
+// keyAlgorithms 返回公钥类型支持的全部签名算法
+func keyAlgorithms(pub any) []string {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return []string{
+			jwt.SigningMethodRS256.Alg(), jwt.SigningMethodRS384.Alg(), jwt.SigningMethodRS512.Alg(),
+			jwt.SigningMethodPS256.Alg(), jwt.SigningMethodPS384.Alg(), jwt.SigningMethodPS512.Alg(),
+		}
+	default:
+		// ECDSA 的算法由曲线决定，Ed25519 只有 EdDSA
+		return []string{defaultAlgorithm(pub)}
+	}
+}
+
+func methodMatchesKey(method jwt.SigningMethod, pub any) bool {
+	switch m := method.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok := pub.([]byte)
+		return ok
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := pub.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		v, ok := pub.(*ecdsa.PublicKey)
+		return ok && v.Curve.Params().BitSize == m.CurveBits
+	case *jwt.SigningMethodEd25519:
+		_, ok := pub.(ed25519.PublicKey)
+		return ok
+	default:
+		return false
+	}
+}
@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeySignAndVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name    string
+		key     any
+		wantAlg string
+	}{
+		{name: "rsa", key: rsaKey, wantAlg: "RS256"},
+		{name: "ecdsa", key: ecKey, wantAlg: "ES256"},
+		{name: "ed25519", key: edKey, wantAlg: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privDER, err := x509.MarshalPKCS8PrivateKey(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubDER, err := x509.MarshalPKIXPublicKey(tt.key.(crypto.Signer).Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signer, err := ParseKeyPEM("kid-1", "", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}))
+			if err != nil {
+				t.Fatalf("ParseKeyPEM() private error = %v", err)
+			}
+			verifier, err := ParseKeyPEM("kid-1", "", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+			if err != nil {
+				t.Fatalf("ParseKeyPEM() public error = %v", err)
+			}
+			if signer.Method.Alg() != tt.wantAlg || verifier.Method.Alg() != tt.wantAlg {
+				t.Fatalf("algorithm = %s/%s, want %s", signer.Method.Alg(), verifier.Method.Alg(), tt.wantAlg)
+			}
+			if verifier.CanSign() {
+				t.Fatal("public key must not be able to sign")
+			}
+
+			token, err := NewJwtTokenWithSigner(signer, time.Now().Unix(), 60, WithOption("userId", "abc"))
+			if err != nil {
+				t.Fatalf("NewJwtTokenWithSigner() error = %v", err)
+			}
+
+			claims, err := ParseJwtTokenWithKeySet(verifier, token)
+			if err != nil {
+				t.Fatalf("ParseJwtTokenWithKeySet() error = %v", err)
+			}
+			if claims["userId"] != "abc" {
+				t.Errorf("claims = %v", claims)
+			}
+
+			other, _ := NewKey("kid-2", "", verifier.Public())
+			if _, err = ParseJwtTokenWithKeySet(other, token); !errors.Is(err, ErrTokenSignatureInvalid) {
+				t.Errorf("ParseJwtTokenWithKeySet() with wrong kid error = %v", err)
+			}
+		})
+	}
+}
+
+func TestKeyRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	verifier, err := NewKey("", "", &rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 使用公钥的 DER 作为 HMAC 密钥伪造令牌
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(pubDER)
+
+	if _, err = ParseJwtTokenWithKeySet(verifier, forged); !errors.Is(err, ErrTokenSignatureInvalid) {
+		t.Errorf("ParseJwtTokenWithKeySet() error = %v, want %v", err, ErrTokenSignatureInvalid)
+	}
+
+	if _, err = NewKey("", "HS256", &rsaKey.PublicKey); !errors.Is(err, ErrAlgorithmInvalid) {
+		t.Errorf("NewKey() error = %v, want %v", err, ErrAlgorithmInvalid)
+	}
+}
@@ -0,0 +1,131 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyRing 持有一个用于签发的活动密钥和若干仅用于校验的历史密钥，按 kid 选择验签密钥，
+// 轮换密钥时旧密钥签发的令牌在过期前仍然有效
+type KeyRing struct {
+	mu     sync.RWMutex
+	active *Key
+	keys   map[string]*Key
+	order  []string
+}
+
+// NewKeyRing 创建密钥环，所有密钥都必须设置唯一的 ID，活动密钥必须持有私钥
+func NewKeyRing(active *Key, retired ...*Key) (*KeyRing, error) {
+	r := &KeyRing{keys: make(map[string]*Key)}
+	for _, k := range retired {
+		if err := r.add(k); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Rotate(active); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Sign 使用活动密钥签发令牌
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	return r.Active().Sign(claims)
+}
+
+// VerificationKey 实现 KeySet，未携带 kid 的令牌使用活动密钥校验
+func (r *KeyRing) VerificationKey(kid, alg string) (any, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	k := r.active
+	if kid != "" {
+		var ok bool
+		if k, ok = r.keys[kid]; !ok {
+			return nil, ErrKeyNotFound
+		}
+	}
+	return k.VerificationKey(kid, alg)
+}
+
+// Active 返回当前的活动密钥
+func (r *KeyRing) Active() *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.active
+}
+
+// Keys 返回全部密钥，活动密钥在前，其余按加入顺序由新到旧
+func (r *KeyRing) Keys() []*Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*Key, 0, len(r.order))
+	for i := len(r.order) - 1; i >= 0; i-- {
+		keys = append(keys, r.keys[r.order[i]])
+	}
+	return keys
+}
+
+// Rotate 将 next 设为活动密钥，原活动密钥保留为历史密钥
+func (r *KeyRing) Rotate(next *Key) error {
+	if next == nil || !next.CanSign() {
+		return ErrKeyCannotSign
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.addLocked(next); err != nil {
+		return err
+	}
+	r.active = next
+	return nil
+}
+
+// Remove 移除历史密钥，之后由其签发的令牌将无法通过校验
+func (r *KeyRing) Remove(kid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active != nil && r.active.ID == kid {
+		return errors.New("jwt: cannot remove the active key")
+	}
+	if _, ok := r.keys[kid]; !ok {
+		return ErrKeyNotFound
+	}
+
+	delete(r.keys, kid)
+	for i, id := range r.order {
+		if id == kid {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *KeyRing) add(k *Key) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.addLocked(k)
+}
+
+func (r *KeyRing) addLocked(k *Key) error {
+	if k == nil || k.ID == "" {
+		return errors.New("jwt: keys in a key ring must have an ID")
+	}
+	if _, ok := r.keys[k.ID]; ok {
+		return fmt.Errorf("jwt: duplicate key ID %q", k.ID)
+	}
+
+	r.keys[k.ID] = k
+	r.order = append(r.order, k.ID)
+	return nil
+}
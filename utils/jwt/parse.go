@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ParseOption describes the options used when parsing a jwt token
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	leeway     time.Duration
+	timeFunc   func() time.Time
+	validators []func(claims jwt.Claims) error
+}
+
+// WithLeeway 设置校验 exp/iat/nbf 时允许的时钟偏差
+func WithLeeway(leeway time.Duration) ParseOption {
+	return func(o *parseOptions) {
+		o.leeway = leeway
+	}
+}
+
+// WithTimeFunc 设置校验时使用的当前时间，默认为 time.Now
+func WithTimeFunc(f func() time.Time) ParseOption {
+	return func(o *parseOptions) {
+		o.timeFunc = f
+	}
+}
+
+// WithRevocation 在签名与有效期校验通过后检查令牌是否已被吊销
+func WithRevocation(ctx context.Context, store *RevocationStore) ParseOption {
+	return func(o *parseOptions) {
+		o.validators = append(o.validators, func(claims jwt.Claims) error {
+			return store.Check(ctx, claims)
+		})
+	}
+}
+
+// ParseJwtToken 校验 NewJwtToken 签发的令牌，并返回其中的全部声明（包括通过 WithOption 附加的自定义数据），数字声明的类型为 json.Number
+func ParseJwtToken(secretKey, tokenString string, opts ...ParseOption) (jwt.MapClaims, error) {
+	claims := make(jwt.MapClaims)
+	keyFunc := func(*jwt.Token) (any, error) {
+		return []byte(secretKey), nil
+	}
+
+	if err := parseToken(tokenString, claims, keyFunc, []string{jwt.SigningMethodHS256.Alg()}, opts); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// VerifyJwtToken 仅校验令牌是否有效
+func VerifyJwtToken(secretKey, tokenString string, opts ...ParseOption) error {
+	_, err := ParseJwtToken(secretKey, tokenString, opts...)
+	return err
+}
+
+// ParseJwtTokenWithKeySet 使用 KeySet 校验令牌，适用于仅持有公钥的服务校验非对称算法签发的令牌
+func ParseJwtTokenWithKeySet(keys KeySet, tokenString string, opts ...ParseOption) (jwt.MapClaims, error) {
+	claims := make(jwt.MapClaims)
+	if err := parseToken(tokenString, claims, keySetFunc(keys), nil, opts); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// VerifyJwtTokenWithKeySet 使用 KeySet 仅校验令牌是否有效
+func VerifyJwtTokenWithKeySet(keys KeySet, tokenString string, opts ...ParseOption) error {
+	_, err := ParseJwtTokenWithKeySet(keys, tokenString, opts...)
+	return err
+}
+
+// keySetFunc 根据令牌头部的 kid 与 alg 从 KeySet 中查找验签密钥
+func keySetFunc(keys KeySet) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keys.VerificationKey(kid, token.Method.Alg())
+	}
+}
+
+// parseToken 解析令牌到 claims，并将校验失败转换为 errorx.CodeError
+func parseToken(tokenString string, claims jwt.Claims, keyFunc jwt.Keyfunc, methods []string, opts []ParseOption) error {
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// 数字声明解码为 json.Number，避免超过 2^53 的 ID 转为 float64 后丢失精度
+	parserOpts := []jwt.ParserOption{
+		jwt.WithJSONNumber(),
+		jwt.WithLeeway(o.leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+	if len(methods) > 0 {
+		parserOpts = append(parserOpts, jwt.WithValidMethods(methods))
+	}
+	if o.timeFunc != nil {
+		parserOpts = append(parserOpts, jwt.WithTimeFunc(o.timeFunc))
+	}
+
+	if _, err := jwt.NewParser(parserOpts...).ParseWithClaims(tokenString, claims, keyFunc); err != nil {
+		return convertError(err)
+	}
+
+	for _, validate := range o.validators {
+		if err := validate(claims); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+// refreshScript 原子地轮换刷新令牌：
+// 提交的是当前令牌则轮换并返回附加数据与令牌族的截止时间，提交的是已使用过的令牌则吊销整个令牌族并返回 -1，否则返回 0。
+// 截止时间在 Issue 时写入，轮换不会延长，没有截止时间的旧令牌族以 ARGV[3] 补写
+var refreshScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'current')
+if not current then
+	return 0
+end
+if current == ARGV[1] then
+	redis.call('HSETNX', KEYS[1], 'deadline', ARGV[3])
+	local deadline = redis.call('HGET', KEYS[1], 'deadline')
+	redis.call('HSET', KEYS[1], 'current', ARGV[2])
+	redis.call('SADD', KEYS[2], ARGV[1])
+	redis.call('PEXPIREAT', KEYS[1], deadline)
+	redis.call('PEXPIREAT', KEYS[2], deadline)
+	return {redis.call('HGET', KEYS[1], 'options'), deadline}
+end
+if redis.call('SISMEMBER', KEYS[2], ARGV[1]) == 1 then
+	redis.call('DEL', KEYS[1], KEYS[2])
+	return -1
+end
+return 0
+`)
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	AccessToken   string `json:"accessToken"`
+	AccessExpire  int64  `json:"accessExpire"`
+	RefreshToken  string `json:"refreshToken"`
+	RefreshExpire int64  `json:"refreshExpire"`
+}
+
+// RefreshManager 签发存储在 Redis 中的不透明刷新令牌。
+// 每次登录生成一个令牌族，刷新令牌每使用一次就轮换，已使用过的刷新令牌再次出现时视为泄露，整个令牌族立即失效
+type RefreshManager struct {
+	Redis         redis.UniversalClient
+	Signer        Signer
+	AccessExpire  time.Duration
+	RefreshExpire time.Duration
+	PreKey        string
+}
+
+// NewRefreshManager returns a refresh token manager using redis
+func NewRefreshManager(r redis.UniversalClient, signer Signer, accessExpire, refreshExpire time.Duration) *RefreshManager {
+	return &RefreshManager{
+		Redis:         r,
+		Signer:        signer,
+		AccessExpire:  accessExpire,
+		RefreshExpire: refreshExpire,
+		PreKey:        config.RedisJwtRefreshPrefix,
+	}
+}
+
+// Issue 创建新的令牌族并返回令牌对，opt 会写入每次签发的访问令牌
+func (m *RefreshManager) Issue(ctx context.Context, opt ...Option) (*TokenPair, error) {
+	family, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := newRefreshToken(family)
+	if err != nil {
+		return nil, err
+	}
+
+	options := make(map[string]any, len(opt))
+	for _, v := range opt {
+		options[v.Key] = v.Val
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to marshal token options: %w", err)
+	}
+
+	// 令牌族的截止时间固定为登录时间加 RefreshExpire，持续轮换也不会延长
+	deadline := time.Now().Add(m.RefreshExpire)
+	familyKey := m.familyKey(family)
+	_, err = m.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, familyKey, "current", hashToken(refreshToken), "options", string(data), "deadline", deadline.UnixMilli())
+		pipe.PExpireAt(ctx, familyKey, deadline)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to store refresh token: %w", err)
+	}
+
+	return m.newPair(refreshToken, deadline, opt)
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧的刷新令牌随即失效
+func (m *RefreshManager) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	family, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, ErrRefreshTokenInvalid
+	}
+	next, err := newRefreshToken(family)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{m.familyKey(family), m.usedKey(family)}
+	res, err := refreshScript.Run(ctx, m.Redis, keys,
+		hashToken(refreshToken), hashToken(next), time.Now().Add(m.RefreshExpire).UnixMilli()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to rotate refresh token: %w", err)
+	}
+
+	switch v := res.(type) {
+	case []any:
+		if len(v) != 2 {
+			break
+		}
+		data, _ := v[0].(string)
+		deadline, err := strconv.ParseInt(fmt.Sprint(v[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid refresh token deadline: %w", err)
+		}
+		// 数字按 json.Number 解码，轮换后的访问令牌保持大整数 ID 的精度
+		var options map[string]any
+		decoder := json.NewDecoder(strings.NewReader(data))
+		decoder.UseNumber()
+		if err = decoder.Decode(&options); err != nil {
+			return nil, fmt.Errorf("jwt: failed to unmarshal token options: %w", err)
+		}
+		opt := make([]Option, 0, len(options))
+		for key, val := range options {
+			opt = append(opt, WithOption(key, val))
+		}
+		return m.newPair(next, time.UnixMilli(deadline), opt)
+	case int64:
+		if v < 0 {
+			return nil, ErrRefreshTokenReused
+		}
+	}
+
+	return nil, ErrRefreshTokenInvalid
+}
+
+// Revoke 吊销刷新令牌所在的整个令牌族，用于退出登录
+func (m *RefreshManager) Revoke(ctx context.Context, refreshToken string) error {
+	family, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return ErrRefreshTokenInvalid
+	}
+
+	return m.Redis.Del(ctx, m.familyKey(family), m.usedKey(family)).Err()
+}
+
+func (m *RefreshManager) newPair(refreshToken string, deadline time.Time, opt []Option) (*TokenPair, error) {
+	now := time.Now()
+	opt = append(opt[:len(opt):len(opt)], WithJti(), WithIssuedAt(now))
+	accessToken, err := NewJwtTokenWithSigner(m.Signer, now.Unix(), int64(m.AccessExpire.Seconds()), opt...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:   accessToken,
+		AccessExpire:  now.Add(m.AccessExpire).Unix(),
+		RefreshToken:  refreshToken,
+		RefreshExpire: deadline.Unix(),
+	}, nil
+}
+
+// familyKey 使用 hash tag 保证同一令牌族的键在集群中位于同一个槽
+func (m *RefreshManager) familyKey(family string) string {
+	return m.PreKey + "{" + family + "}:FAMILY"
+}
+
+func (m *RefreshManager) usedKey(family string) string {
+	return m.PreKey + "{" + family + "}:USED"
+}
+
+// newRefreshToken 生成形如 family.secret 的不透明刷新令牌
+func newRefreshToken(family string) (string, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	return family + "." + secret, nil
+}
+
+func parseRefreshToken(token string) (family string, ok bool) {
+	family, secret, ok := strings.Cut(token, ".")
+	if !ok || family == "" || secret == "" {
+		return "", false
+	}
+	return family, true
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New("jwt: failed to generate random bytes")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,94 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRefreshManager(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	secretKey := "jS6VKDtsJf3z1n2VKDtsJf3z1n2"
+	m := NewRefreshManager(rds, NewHMACKey("", secretKey), time.Minute, time.Hour)
+
+	first, err := m.Issue(ctx, WithOption("userId", "abc"))
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	second, err := m.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh() must rotate the refresh token")
+	}
+	if second.RefreshExpire != first.RefreshExpire {
+		t.Errorf("Refresh() extended the family deadline: %d, want %d", second.RefreshExpire, first.RefreshExpire)
+	}
+
+	claims, err := ParseJwtToken(secretKey, second.AccessToken)
+	if err != nil {
+		t.Fatalf("ParseJwtToken() error = %v", err)
+	}
+	if claims["userId"] != "abc" {
+		t.Errorf("claims = %v", claims)
+	}
+
+	// 轮换后的访问令牌保持大整数的精度
+	big, err := m.Issue(ctx, WithOption("userId", int64(1234567890123456789)))
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if big, err = m.Refresh(ctx, big.RefreshToken); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if claims, err = ParseJwtToken(secretKey, big.AccessToken); err != nil || claims["userId"] != json.Number("1234567890123456789") {
+		t.Errorf("claims = %v, %v", claims, err)
+	}
+
+	// 重放已使用的刷新令牌会吊销整个令牌族
+	if _, err = m.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh() reused error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, err = m.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("Refresh() after family revoked error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	third, _ := m.Issue(ctx)
+	if err = m.Revoke(ctx, third.RefreshToken); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err = m.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() after revoke error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+	if _, err = m.Refresh(ctx, "garbage"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() garbage error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	// 持续轮换不会延长令牌族的有效期
+	now := time.Now()
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		mr.SetTime(now)
+		mr.FastForward(d)
+	}
+	advance(0)
+	fourth, _ := m.Issue(ctx)
+	advance(40 * time.Minute)
+	if fourth, err = m.Refresh(ctx, fourth.RefreshToken); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	advance(30 * time.Minute)
+	if _, err = m.Refresh(ctx, fourth.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() after family deadline error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
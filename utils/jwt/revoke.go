@@ -0,0 +1,212 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+const (
+	defaultUserClaim = "userId"
+	// defaultMaxTokenLife 未设置 MaxTokenLife 时用户级吊销记录的保留时长，避免记录永久保存
+	defaultMaxTokenLife = 30 * 24 * time.Hour
+)
+
+// RevocationStore 在 Redis 中记录被吊销的令牌，用于退出登录和强制下线
+type RevocationStore struct {
+	Redis        redis.UniversalClient
+	PreKey       string
+	UserClaim    string        // 标识用户的声明名称，默认为 userId
+	MaxTokenLife time.Duration // 令牌的最长有效期，用户级吊销记录保留该时长，不大于 0 时为 30 天
+}
+
+// NewRevocationStore returns a revocation store using redis, non-positive maxTokenLife falls back to 30 days
+func NewRevocationStore(r redis.UniversalClient, maxTokenLife time.Duration) *RevocationStore {
+	if maxTokenLife <= 0 {
+		maxTokenLife = defaultMaxTokenLife
+	}
+	return &RevocationStore{
+		Redis:        r,
+		PreKey:       config.RedisJwtRevokePrefix,
+		UserClaim:    defaultUserClaim,
+		MaxTokenLife: maxTokenLife,
+	}
+}
+
+// Revoke 吊销指定 jti 的令牌，记录保留到令牌过期为止
+func (s *RevocationStore) Revoke(ctx context.Context, jti string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+
+	return s.Redis.Set(ctx, s.jtiKey(jti), 1, ttl).Err()
+}
+
+// RevokeToken 吊销已解析的令牌，令牌必须携带 jti
+func (s *RevocationStore) RevokeToken(ctx context.Context, claims jwt.Claims) error {
+	m, err := toMapClaims(claims)
+	if err != nil {
+		return err
+	}
+	jti := claimString(m["jti"])
+	if jti == "" {
+		return errors.New("jwt: token has no jti and cannot be revoked individually")
+	}
+	exp, err := m.GetExpirationTime()
+	if err != nil || exp == nil {
+		return errors.New("jwt: token has no valid exp")
+	}
+
+	return s.Revoke(ctx, jti, exp.Time)
+}
+
+// RevokeUser 吊销用户在 before 及之前签发的全部令牌，按毫秒比较。
+// 只有通过 WithIssuedAt 签发的令牌 iat 精确到毫秒，iat 只精确到秒的令牌按该秒的开始时间比较
+func (s *RevocationStore) RevokeUser(ctx context.Context, userId string, before time.Time) error {
+	return s.Redis.Set(ctx, s.userKey(userId), before.UnixMilli(), s.maxTokenLife()).Err()
+}
+
+// IsRevoked 判断令牌是否已被吊销
+func (s *RevocationStore) IsRevoked(ctx context.Context, claims jwt.Claims) (bool, error) {
+	m, err := toMapClaims(claims)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		jtiCmd  *redis.IntCmd
+		userCmd *redis.StringCmd
+	)
+	jti := claimString(m["jti"])
+	userId := claimString(m[s.userClaim()])
+	_, err = s.Redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if jti != "" {
+			jtiCmd = pipe.Exists(ctx, s.jtiKey(jti))
+		}
+		if userId != "" {
+			userCmd = pipe.Get(ctx, s.userKey(userId))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("jwt: failed to check revocation: %w", err)
+	}
+
+	if jtiCmd != nil && jtiCmd.Val() > 0 {
+		return true, nil
+	}
+	if userCmd != nil && userCmd.Err() == nil {
+		before, err := userCmd.Int64()
+		if err != nil {
+			return false, fmt.Errorf("jwt: invalid revocation record: %w", err)
+		}
+		iat, ok := issuedAtMilli(m["iat"])
+		if !ok || iat <= before {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Check 令牌已被吊销时返回 ErrTokenRevoked
+func (s *RevocationStore) Check(ctx context.Context, claims jwt.Claims) error {
+	revoked, err := s.IsRevoked(ctx, claims)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// issuedAtMilli 返回 iat 的毫秒时间戳，保留 iat 的小数部分
+func issuedAtMilli(v any) (int64, bool) {
+	var (
+		sec float64
+		err error
+	)
+	switch n := v.(type) {
+	case json.Number:
+		sec, err = n.Float64()
+	case float64:
+		sec = n
+	default:
+		return 0, false
+	}
+	if err != nil {
+		return 0, false
+	}
+	return int64(math.Round(sec * 1000)), true
+}
+
+func (s *RevocationStore) maxTokenLife() time.Duration {
+	if s.MaxTokenLife <= 0 {
+		return defaultMaxTokenLife
+	}
+	return s.MaxTokenLife
+}
+
+func (s *RevocationStore) userClaim() string {
+	if s.UserClaim == "" {
+		return defaultUserClaim
+	}
+	return s.UserClaim
+}
+
+func (s *RevocationStore) jtiKey(jti string) string {
+	return s.PreKey + "JTI:" + jti
+}
+
+func (s *RevocationStore) userKey(userId string) string {
+	return s.PreKey + "USER:" + userId
+}
+
+// toMapClaims 将任意声明转换为 MapClaims，便于按名称读取自定义声明
+func toMapClaims(claims jwt.Claims) (jwt.MapClaims, error) {
+	if m, ok := claims.(jwt.MapClaims); ok {
+		return m, nil
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to marshal claims: %w", err)
+	}
+	// 数字按 json.Number 解码，超过 2^53 的用户 ID 不会丢失精度
+	var m jwt.MapClaims
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("jwt: failed to unmarshal claims: %w", err)
+	}
+	return m, nil
+}
+
+// claimString 将字符串或数字类型的声明格式化为字符串
+func claimString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return val.String()
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	default:
+		return ""
+	}
+}
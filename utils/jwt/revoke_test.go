@@ -0,0 +1,115 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRevocationStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	secretKey := "jS6VKDtsJf3z1n2VKDtsJf3z1n2"
+	store := NewRevocationStore(rds, time.Hour)
+	now := time.Now().Unix()
+
+	single, _ := NewJwtToken(secretKey, now, 60, WithOption("userId", "abc"), WithJti())
+	other, _ := NewJwtToken(secretKey, now, 60, WithOption("userId", "abc"), WithJti())
+	numeric, _ := NewJwtToken(secretKey, now, 60, WithOption("userId", 10086))
+
+	claims, err := ParseJwtToken(secretKey, single, WithRevocation(ctx, store))
+	if err != nil {
+		t.Fatalf("ParseJwtToken() error = %v", err)
+	}
+
+	// 吊销单个令牌不影响同一用户的其他令牌
+	if err = store.RevokeToken(ctx, claims); err != nil {
+		t.Fatalf("RevokeToken() error = %v", err)
+	}
+	if mr.TTL(store.jtiKey(claims["jti"].(string))) <= 0 {
+		t.Error("revoked jti must expire with the token")
+	}
+	if err = VerifyJwtToken(secretKey, single, WithRevocation(ctx, store)); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyJwtToken() revoked error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if err = VerifyJwtToken(secretKey, other, WithRevocation(ctx, store)); err != nil {
+		t.Errorf("VerifyJwtToken() other error = %v", err)
+	}
+
+	// 强制下线吊销用户此前签发的全部令牌，包括与 before 同一秒签发的令牌
+	if err = store.RevokeUser(ctx, "abc", time.Unix(now, 0)); err != nil {
+		t.Fatalf("RevokeUser() error = %v", err)
+	}
+	if err = VerifyJwtToken(secretKey, other, WithRevocation(ctx, store)); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyJwtToken() after RevokeUser error = %v, want %v", err, ErrTokenRevoked)
+	}
+	fresh, _ := NewJwtToken(secretKey, now+1, 60, WithOption("userId", "abc"))
+	if err = VerifyJwtToken(secretKey, fresh, WithRevocation(ctx, store), WithLeeway(time.Minute)); err != nil {
+		t.Errorf("VerifyJwtToken() token issued after RevokeUser error = %v", err)
+	}
+
+	_ = store.RevokeUser(ctx, "10086", time.Unix(now, 0))
+	if err = VerifyJwtToken(secretKey, numeric, WithRevocation(ctx, store)); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyJwtToken() numeric userId error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	// 类型化声明中超过 2^53 的数字用户 ID
+	type userClaims struct {
+		UserId int64 `json:"userId"`
+		jwt.RegisteredClaims
+	}
+	big := &userClaims{UserId: 1234567890123456789, RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Unix(now, 0))}}
+	_ = store.RevokeUser(ctx, "1234567890123456789", time.Unix(now, 0))
+	if revoked, err := store.IsRevoked(ctx, big); err != nil || !revoked {
+		t.Errorf("IsRevoked() large numeric userId = %v, %v", revoked, err)
+	}
+}
+
+func TestRevokeUserSameSecond(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRevocationStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Hour)
+	ctx := context.Background()
+
+	secretKey := "jS6VKDtsJf3z1n2VKDtsJf3z1n2"
+	// 强制下线与重新登录发生在同一秒内
+	revokedAt := time.Unix(time.Now().Unix(), int64(500*time.Millisecond))
+	issue := func(at time.Time) string {
+		token, _ := NewJwtToken(secretKey, at.Unix(), 60, WithOption("userId", "abc"), WithIssuedAt(at))
+		return token
+	}
+	before := issue(revokedAt.Add(-100 * time.Millisecond))
+	after := issue(revokedAt.Add(100 * time.Millisecond))
+
+	if err := store.RevokeUser(ctx, "abc", revokedAt); err != nil {
+		t.Fatalf("RevokeUser() error = %v", err)
+	}
+	if err := VerifyJwtToken(secretKey, before, WithRevocation(ctx, store)); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyJwtToken() token issued before RevokeUser error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if err := VerifyJwtToken(secretKey, after, WithRevocation(ctx, store), WithLeeway(time.Second)); err != nil {
+		t.Errorf("VerifyJwtToken() token issued after RevokeUser in the same second error = %v", err)
+	}
+}
+
+func TestRevokeUserDefaultTokenLife(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	// 未设置最长有效期时吊销记录仍然会过期
+	for _, store := range []*RevocationStore{NewRevocationStore(rds, 0), {Redis: rds}} {
+		if err := store.RevokeUser(ctx, "abc", time.Now()); err != nil {
+			t.Fatalf("RevokeUser() error = %v", err)
+		}
+		if ttl := mr.TTL(store.userKey("abc")); ttl != defaultMaxTokenLife {
+			t.Errorf("RevokeUser() ttl = %s, want %s", ttl, defaultMaxTokenLife)
+		}
+	}
+}
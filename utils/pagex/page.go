@@ -44,3 +44,9 @@ func GetPaperIndexPage(progress, pageSize int) (progressPage, index int) {
 
 	return progressPage, index
 }
+
+// CursorPage 游标分页的结果，适用于无法计算总数的列表，NextToken 为空表示没有更多数据
+type CursorPage[T any] struct {
+	List      []T    `json:"list"`
+	NextToken string `json:"nextToken,omitempty"`
+}
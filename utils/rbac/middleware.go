@@ -0,0 +1,35 @@
+package rbac
+
+import (
+	"net/http"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zhanghaidi/zero-common/utils/auth"
+	jwtx "github.com/zhanghaidi/zero-common/utils/jwt"
+	"github.com/zhanghaidi/zero-common/utils/response"
+)
+
+// Require 返回校验权限的 go-zero 中间件，需放在 auth.JwtMiddleware 之后
+func (e *Enforcer) Require(perm string) func(next http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := auth.ClaimsFromContext(r.Context()); !ok {
+				response.Response(w, nil, jwtx.ErrTokenMissing)
+				return
+			}
+
+			ok, err := e.Can(r.Context(), perm)
+			if err != nil {
+				logx.WithContext(r.Context()).Errorw("failed to check permission", logx.Field("perm", perm), logx.Field("detail", err))
+				response.Response(w, nil, ErrPermissionUnavailable)
+				return
+			}
+			if !ok {
+				response.Response(w, nil, ErrPermissionDenied)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zhanghaidi/zero-common/config"
+	"github.com/zhanghaidi/zero-common/define"
+	"github.com/zhanghaidi/zero-common/utils/auth"
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+	"gorm.io/gorm"
+)
+
+const CodePermissionDenied = 40300
+
+var (
+	ErrPermissionDenied = errorx.NewCodeError(CodePermissionDenied, "没有操作权限")
+	// ErrPermissionUnavailable 加载权限出错时返回，不向客户端暴露内部错误信息
+	ErrPermissionUnavailable = errorx.NewDefaultError("权限服务暂不可用，请稍后重试")
+)
+
+// RolePermission 角色与权限的对应关系，权限使用冒号分隔的层级字符串，如 order:create、order:*
+type RolePermission struct {
+	Id         int64  `gorm:"primaryKey"`
+	RoleId     int64  `gorm:"index;not null"`
+	Permission string `gorm:"size:128;not null"`
+}
+
+// Enforcer 从数据库加载角色权限并缓存到 Redis
+type Enforcer struct {
+	DB         *gorm.DB              // 为空时使用 define.GlobalDatabase
+	Redis      redis.UniversalClient // 为空时使用 define.GlobalRedis，二者都为空时不缓存
+	Expiration time.Duration
+	PreKey     string
+	Table      string // 自定义表名，为空时使用 RolePermission 的表名
+}
+
+// NewEnforcer returns a permission enforcer, nil db or redis falls back to the global ones
+func NewEnforcer(db *gorm.DB, r redis.UniversalClient) *Enforcer {
+	return &Enforcer{
+		DB:         db,
+		Redis:      r,
+		Expiration: time.Minute * 10,
+		PreKey:     config.RedisRbacPrefix,
+	}
+}
+
+// Can 判断上下文中的当前用户角色是否拥有权限，需配合 auth.JwtMiddleware 使用
+func (e *Enforcer) Can(ctx context.Context, perm string) (bool, error) {
+	roleId := auth.RoleIdFromContext(ctx)
+	if roleId == 0 {
+		return false, nil
+	}
+
+	return e.HasPermission(ctx, roleId, perm)
+}
+
+// HasPermission 判断角色是否拥有权限
+func (e *Enforcer) HasPermission(ctx context.Context, roleId int64, perm string) (bool, error) {
+	perms, err := e.Permissions(ctx, roleId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, granted := range perms {
+		if Match(granted, perm) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Permissions 返回角色的全部权限，优先读取缓存
+func (e *Enforcer) Permissions(ctx context.Context, roleId int64) ([]string, error) {
+	rds := e.redis()
+	key := e.PreKey + strconv.FormatInt(roleId, 10)
+
+	if rds != nil {
+		val, err := rds.Get(ctx, key).Result()
+		if err == nil {
+			var perms []string
+			if err = json.Unmarshal([]byte(val), &perms); err == nil {
+				return perms, nil
+			}
+		}
+		if err != nil && !errors.Is(err, redis.Nil) {
+			logx.WithContext(ctx).Errorw("error occurs when role permissions gets from redis", logx.Field("detail", err))
+		}
+	}
+
+	perms, err := e.load(ctx, roleId)
+	if err != nil {
+		return nil, err
+	}
+
+	if rds != nil {
+		// 空权限同样缓存，避免无权限角色反复查询数据库
+		data, _ := json.Marshal(perms)
+		if err = rds.Set(ctx, key, data, e.Expiration).Err(); err != nil {
+			logx.WithContext(ctx).Errorw("error occurs when role permissions sets to redis", logx.Field("detail", err))
+		}
+	}
+
+	return perms, nil
+}
+
+// Invalidate 清除角色的权限缓存，修改角色权限后调用
+func (e *Enforcer) Invalidate(ctx context.Context, roleIds ...int64) error {
+	rds := e.redis()
+	if rds == nil || len(roleIds) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(roleIds))
+	for _, id := range roleIds {
+		keys = append(keys, e.PreKey+strconv.FormatInt(id, 10))
+	}
+	_, err := rds.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+func (e *Enforcer) load(ctx context.Context, roleId int64) ([]string, error) {
+	db := e.DB
+	if db == nil {
+		db = define.GlobalDatabase
+	}
+	if db == nil {
+		return nil, errors.New("rbac: database is not initialized")
+	}
+
+	tx := db.WithContext(ctx)
+	if e.Table != "" {
+		tx = tx.Table(e.Table)
+	} else {
+		tx = tx.Model(&RolePermission{})
+	}
+
+	perms := make([]string, 0)
+	if err := tx.Where("role_id = ?", roleId).Pluck("permission", &perms).Error; err != nil {
+		return nil, fmt.Errorf("rbac: failed to load role permissions: %w", err)
+	}
+	return perms, nil
+}
+
+func (e *Enforcer) redis() redis.UniversalClient {
+	if e.Redis != nil {
+		return e.Redis
+	}
+	return define.GlobalRedis
+}
+
+// Match 判断已授予的权限是否覆盖所需权限，* 匹配任意一段，位于末尾时匹配其后的所有层级
+func Match(granted, required string) bool {
+	g := strings.Split(granted, ":")
+	r := strings.Split(required, ":")
+
+	for i, seg := range g {
+		if seg == "*" && i == len(g)-1 {
+			return len(r) >= len(g)
+		}
+		if i >= len(r) || (seg != "*" && seg != r[i]) {
+			return false
+		}
+	}
+	return len(g) == len(r)
+}
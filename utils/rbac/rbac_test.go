@@ -0,0 +1,126 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanghaidi/zero-common/utils/auth"
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+	"github.com/zhanghaidi/zero-common/utils/response"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		granted  string
+		required string
+		want     bool
+	}{
+		{"order:create", "order:create", true},
+		{"order:create", "order:delete", false},
+		{"order:*", "order:create", true},
+		{"order:*", "order:item:delete", true},
+		{"order:*", "order", false},
+		{"order:*:read", "order:item:read", true},
+		{"order:*:read", "order:item:write", false},
+		{"*", "user:delete", true},
+		{"order", "order:create", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.granted, tt.required); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestEnforcerCan(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&RolePermission{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&[]RolePermission{
+		{RoleId: 1, Permission: "order:*"},
+		{RoleId: 2, Permission: "order:read"},
+	})
+
+	mr := miniredis.RunT(t)
+	e := NewEnforcer(db, redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	ctxWithRole := func(roleId int) context.Context {
+		return auth.WithClaims(context.Background(), auth.NewClaims(jwt.MapClaims{auth.ClaimRoleId: float64(roleId)}))
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		perm string
+		want bool
+	}{
+		{name: "wildcard", ctx: ctxWithRole(1), perm: "order:delete", want: true},
+		{name: "exact", ctx: ctxWithRole(2), perm: "order:read", want: true},
+		{name: "denied", ctx: ctxWithRole(2), perm: "order:delete", want: false},
+		{name: "no permissions", ctx: ctxWithRole(3), perm: "order:read", want: false},
+		{name: "unauthenticated", ctx: context.Background(), perm: "order:read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Can(tt.ctx, tt.perm)
+			if err != nil {
+				t.Fatalf("Can() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Can() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 缓存生效后修改数据库需要清除缓存
+	db.Create(&RolePermission{RoleId: 2, Permission: "order:delete"})
+	if ok, _ := e.Can(ctxWithRole(2), "order:delete"); ok {
+		t.Error("Can() should read cached permissions")
+	}
+	_ = e.Invalidate(context.Background(), 2)
+	if ok, _ := e.Can(ctxWithRole(2), "order:delete"); !ok {
+		t.Error("Can() should reload permissions after Invalidate")
+	}
+}
+
+func TestRequireError(t *testing.T) {
+	// 未建表时查询权限出错
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := miniredis.RunT(t)
+	e := NewEnforcer(db, redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	handler := e.Require("order:read")(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called when permission check failed")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(auth.WithClaims(req.Context(), auth.NewClaims(jwt.MapClaims{auth.ClaimRoleId: float64(1)})))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	// 数据库错误不能透传给客户端
+	var body response.Body
+	if err = json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+	}
+	if body.Code != errorx.DefaultCode || body.Msg != ErrPermissionUnavailable.Error() {
+		t.Errorf("body = %+v", body)
+	}
+}
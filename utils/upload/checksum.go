@@ -0,0 +1,109 @@
+package upload
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+)
+
+// ErrChecksumMismatch 分片或对象内容与校验值不一致
+var ErrChecksumMismatch = errors.New("upload: checksum mismatch")
+
+// crc64Table 与 OSS、COS 一致的 CRC64 ECMA 表
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// PartOption 上传分片的可选参数
+type PartOption func(*partOptions)
+
+type partOptions struct {
+	contentMD5 string // Base64 编码的 MD5，与 Content-MD5 请求头格式一致
+	crc64      uint64
+	hasCRC64   bool
+}
+
+// WithContentMD5 校验分片的 MD5，md5 为 Base64 编码，与 Content-MD5 请求头格式一致
+func WithContentMD5(md5 string) PartOption {
+	return func(o *partOptions) {
+		o.contentMD5 = md5
+	}
+}
+
+// WithCRC64 校验分片的 CRC64 ECMA
+func WithCRC64(crc uint64) PartOption {
+	return func(o *partOptions) {
+		o.crc64 = crc
+		o.hasCRC64 = true
+	}
+}
+
+func newPartOptions(opts []PartOption) partOptions {
+	var o partOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// hashReader 读取数据的同时计算 MD5 与 CRC64
+type hashReader struct {
+	reader io.Reader
+	md5    hash.Hash
+	crc64  hash.Hash64
+	size   int64
+}
+
+func newHashReader(reader io.Reader) *hashReader {
+	return &hashReader{reader: reader, md5: md5.New(), crc64: crc64.New(crc64Table)}
+}
+
+func (r *hashReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.md5.Write(p[:n])
+		r.crc64.Write(p[:n])
+		r.size += int64(n)
+	}
+	return n, err
+}
+
+// MD5 返回已读取数据的 MD5，十六进制
+func (r *hashReader) MD5() string {
+	return hex.EncodeToString(r.md5.Sum(nil))
+}
+
+// CRC64 返回已读取数据的 CRC64 ECMA
+func (r *hashReader) CRC64() uint64 {
+	return r.crc64.Sum64()
+}
+
+// abortMismatch 分片写入服务端后才校验失败时取消整个上传，避免不一致的分片被 ListParts 当作有效分片，
+// 返回的错误同时匹配 ErrChecksumMismatch 与 ErrSessionNotFound，客户端需要重新初始化上传
+func abortMismatch(ctx context.Context, err error, abort func(ctx context.Context) error) error {
+	if abortErr := abort(context.WithoutCancel(ctx)); abortErr != nil {
+		return fmt.Errorf("%w (abort multipart upload: %v)", err, abortErr)
+	}
+	return fmt.Errorf("%w, multipart upload aborted: %w", err, ErrSessionNotFound)
+}
+
+// verify 校验已读取的数据与客户端提供的校验值是否一致
+func (r *hashReader) verify(o partOptions) error {
+	if o.contentMD5 != "" {
+		want, err := base64.StdEncoding.DecodeString(o.contentMD5)
+		if err != nil {
+			return fmt.Errorf("%w: invalid content md5 %q", ErrChecksumMismatch, o.contentMD5)
+		}
+		if got := r.md5.Sum(nil); hex.EncodeToString(got) != hex.EncodeToString(want) {
+			return fmt.Errorf("%w: md5 %x, want %x", ErrChecksumMismatch, got, want)
+		}
+	}
+	if o.hasCRC64 && r.CRC64() != o.crc64 {
+		return fmt.Errorf("%w: crc64 %d, want %d", ErrChecksumMismatch, r.CRC64(), o.crc64)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// upload/cos.go
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+// CosUploader 腾讯云对象存储 COS，使用与 S3 兼容的 XML API
+type CosUploader struct {
+	*objectStore
+}
+
+func init() {
+	Register("cos", func(cfg config.StorageConf) (Uploader, error) {
+		return NewCosUploader(cfg)
+	})
+}
+
+func NewCosUploader(cfg config.StorageConf) (*CosUploader, error) {
+	c := cfg.Cos
+	if c.BucketURL == "" {
+		return nil, errors.New("failed to create COS client: bucket url is required")
+	}
+	bucketURL := c.BucketURL
+	if !strings.Contains(bucketURL, "://") {
+		bucketURL = "https://" + bucketURL
+	}
+	baseURL, err := url.Parse(bucketURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create COS client: %w", err)
+	}
+
+	host := baseURL.Host
+	client := &objectClient{
+		baseURL: baseURL,
+		client:  &http.Client{},
+		signer: &cosSigner{
+			secretID:  c.SecretID,
+			secretKey: c.SecretKey,
+			now:       time.Now,
+		},
+		headerPrefix: "x-cos-",
+		copySource: func(key string) string {
+			return host + "/" + uriEncode(key, false)
+		},
+	}
+
+	return &CosUploader{objectStore: &objectStore{
+		urlResolver: urlResolver{cdnURL: cfg.CdnURL, private: cfg.Private},
+		client:      client,
+		name:        "COS",
+		publicURL:   baseURL.String(),
+	}}, nil
+}
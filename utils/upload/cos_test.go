@@ -0,0 +1,115 @@
+package upload
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+func newTestCosUploader(t *testing.T) (*CosUploader, *fakeObjectServer) {
+	var host string
+	fake := newFakeObjectServer("", func(source string) string {
+		key, _ := url.PathUnescape(strings.TrimPrefix(source, host+"/"))
+		return key
+	})
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	host = strings.TrimPrefix(srv.URL, "http://")
+
+	var cfg config.StorageConf
+	cfg.Driver = "cos"
+	cfg.Cos.BucketURL = srv.URL
+	cfg.Cos.SecretID = "AKIDQjz3ltompVjBni5LitkWHFlFpwkn9U5q"
+	cfg.Cos.SecretKey = "BQYIM75p8x0iWVFSIgqEKwFprpRSVHlz"
+
+	u, err := NewUploader(cfg)
+	if err != nil {
+		t.Fatalf("NewUploader() error = %v", err)
+	}
+	return u.(*CosUploader), fake
+}
+
+func TestCosUploader(t *testing.T) {
+	u, fake := newTestCosUploader(t)
+	testUploader(t, u)
+
+	if got := string(fake.objects["course/c/1.mp4"]); got != "video-1" {
+		t.Errorf("copied object = %q", got)
+	}
+}
+
+func TestCosSigner(t *testing.T) {
+	signer := &cosSigner{
+		secretID:  "AKIDQjz3ltompVjBni5LitkWHFlFpwkn9U5q",
+		secretKey: "BQYIM75p8x0iWVFSIgqEKwFprpRSVHlz",
+		now:       func() time.Time { return time.Unix(1557989151, 0) },
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://examplebucket-1250000000.cos.ap-beijing.myqcloud.com/exampleobject?Prefix=a%2F&max-keys=10", nil)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Cos-Acl", "private")
+	req.Header.Set("User-Agent", "test")
+	signer.sign(req)
+
+	fields := make(map[string]string)
+	for _, pair := range strings.Split(req.Header.Get("Authorization"), "&") {
+		k, v, _ := strings.Cut(pair, "=")
+		fields[k] = v
+	}
+	want := map[string]string{
+		"q-sign-algorithm": "sha1",
+		"q-ak":             signer.secretID,
+		"q-sign-time":      "1557989151;1557992751",
+		"q-key-time":       "1557989151;1557992751",
+		"q-header-list":    "content-type;host;x-cos-acl",
+		"q-url-param-list": "max-keys;prefix",
+	}
+	for k, v := range want {
+		if got := fields[k]; got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if len(fields["q-signature"]) != 40 {
+		t.Errorf("q-signature = %q", fields["q-signature"])
+	}
+
+	// 相同请求的签名应当稳定
+	first := req.Header.Get("Authorization")
+	signer.sign(req)
+	if got := req.Header.Get("Authorization"); got != first {
+		t.Errorf("signature not deterministic: %q != %q", got, first)
+	}
+}
+
+func TestCosUploaderPresign(t *testing.T) {
+	u, fake := newTestCosUploader(t)
+
+	putURL, err := u.PresignPut("avatar/1.png", PresignOptions{ContentType: "image/png", Expires: time.Minute})
+	if err != nil {
+		t.Fatalf("PresignPut() error = %v", err)
+	}
+	parsed, _ := url.Parse(putURL)
+	query := parsed.Query()
+	if got := query.Get("q-header-list"); got != "content-type;host" {
+		t.Errorf("q-header-list = %q", got)
+	}
+	if start, end, _ := strings.Cut(query.Get("q-sign-time"), ";"); start == "" || end == "" {
+		t.Errorf("q-sign-time = %q", query.Get("q-sign-time"))
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, putURL, strings.NewReader("png"))
+	req.Header.Set("Content-Type", "image/png")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT presigned url = %v, %v", resp, err)
+	}
+	resp.Body.Close()
+	if got := string(fake.objects["avatar/1.png"]); got != "png" {
+		t.Errorf("uploaded object = %q", got)
+	}
+}
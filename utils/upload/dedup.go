@@ -0,0 +1,304 @@
+package upload
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanghaidi/zero-common/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrHashNotFound 索引中不存在该内容哈希
+	ErrHashNotFound = errors.New("upload: content hash not found")
+	// ErrInvalidHash 内容哈希不是 32 位十六进制的 MD5
+	ErrInvalidHash = errors.New("upload: invalid content hash")
+)
+
+// hashPattern 内容哈希为小写十六进制的 MD5，与 Partial.CalculateHash 一致
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// HashEntry 内容哈希对应的对象
+type HashEntry struct {
+	Hash      string `gorm:"primaryKey;size:32"`
+	ObjectKey string `gorm:"size:1024;not null"`
+	Size      int64
+	ETag      string `gorm:"column:etag;size:128"` // 记录时对象的 ETag，对象被覆盖后不一致
+	CreatedAt time.Time
+}
+
+// TableName 未设置表前缀时使用 upload_hash
+func (HashEntry) TableName() string {
+	return "upload_hash"
+}
+
+// HashIndex 内容哈希到对象键的索引
+type HashIndex interface {
+	// Get 获取哈希对应的对象，不存在时返回 ErrHashNotFound
+	Get(ctx context.Context, hash string) (*HashEntry, error)
+	Put(ctx context.Context, entry *HashEntry) error
+	Delete(ctx context.Context, hash string) error
+}
+
+// RedisHashIndex 将索引保存在 Redis 中，不设置过期时间
+type RedisHashIndex struct {
+	Redis  redis.UniversalClient
+	PreKey string
+}
+
+// NewRedisHashIndex returns a hash index using redis
+func NewRedisHashIndex(r redis.UniversalClient) *RedisHashIndex {
+	return &RedisHashIndex{
+		Redis:  r,
+		PreKey: config.RedisUploadHashPrefix,
+	}
+}
+
+func (s *RedisHashIndex) Get(ctx context.Context, hash string) (*HashEntry, error) {
+	fields, err := s.Redis.HGetAll(ctx, s.PreKey+hash).Result()
+	if err != nil {
+		return nil, err
+	}
+	objectKey, ok := fields["objectKey"]
+	if !ok {
+		return nil, ErrHashNotFound
+	}
+
+	entry := &HashEntry{Hash: hash, ObjectKey: objectKey}
+	entry.Size, _ = strconv.ParseInt(fields["size"], 10, 64)
+	entry.ETag = fields["etag"]
+	entry.CreatedAt, _ = time.Parse(time.RFC3339, fields["createdAt"])
+	return entry, nil
+}
+
+func (s *RedisHashIndex) Put(ctx context.Context, entry *HashEntry) error {
+	return s.Redis.HSet(ctx, s.PreKey+entry.Hash,
+		"objectKey", entry.ObjectKey,
+		"size", entry.Size,
+		"etag", entry.ETag,
+		"createdAt", entry.CreatedAt.Format(time.RFC3339),
+	).Err()
+}
+
+func (s *RedisHashIndex) Delete(ctx context.Context, hash string) error {
+	return s.Redis.Del(ctx, s.PreKey+hash).Err()
+}
+
+// GormHashIndex 将索引保存在数据库表 upload_hash 中，使用前需要 AutoMigrate(&HashEntry{})
+type GormHashIndex struct {
+	DB *gorm.DB
+}
+
+// NewGormHashIndex returns a hash index using gorm
+func NewGormHashIndex(db *gorm.DB) *GormHashIndex {
+	return &GormHashIndex{DB: db}
+}
+
+func (s *GormHashIndex) Get(ctx context.Context, hash string) (*HashEntry, error) {
+	var entry HashEntry
+	if err := s.DB.WithContext(ctx).Where("hash = ?", hash).Take(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHashNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *GormHashIndex) Put(ctx context.Context, entry *HashEntry) error {
+	return s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"object_key", "size", "etag", "created_at"}),
+	}).Create(entry).Error
+}
+
+func (s *GormHashIndex) Delete(ctx context.Context, hash string) error {
+	return s.DB.WithContext(ctx).Where("hash = ?", hash).Delete(&HashEntry{}).Error
+}
+
+// dedupStagingPrefix 校验哈希前暂存上传内容的前缀
+const dedupStagingPrefix = "chunk-upload/_dedup/"
+
+// Deduplicator 基于内容哈希的秒传：内容已存在时在存储内将已有对象复制到调用方的对象键，否则走正常的上传流程并记录哈希。
+// 知道哈希与大小即可取得文件内容，需要按用户隔离的私有文件不应开启秒传
+type Deduplicator struct {
+	uploader Uploader
+	index    HashIndex
+}
+
+// NewDeduplicator 创建秒传处理器
+func NewDeduplicator(uploader Uploader, index HashIndex) *Deduplicator {
+	return &Deduplicator{uploader: uploader, index: index}
+}
+
+// Instant 查找与客户端提供的哈希和大小一致的已有对象，命中时复制到 objectKey，已有对象的键不会返回给调用方，
+// 复制后两个对象互不影响，删除任意一个都不会影响另一个。未命中时返回 ErrHashNotFound，客户端需要继续正常上传
+func (d *Deduplicator) Instant(ctx context.Context, objectKey, hash string, size int64) (*CompleteResult, error) {
+	entry, err := d.lookup(ctx, hash, size)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.uploader.CopyFileCtx(ctx, entry.ObjectKey, objectKey); err != nil {
+		// 查找后对象被删除
+		if errors.Is(err, ErrObjectNotFound) {
+			_ = d.index.Delete(ctx, entry.Hash)
+			return nil, ErrHashNotFound
+		}
+		return nil, err
+	}
+	return &CompleteResult{ObjectKey: objectKey, Size: entry.Size, MD5: entry.Hash}, nil
+}
+
+// lookup 查找哈希对应的已有对象，对象已被删除或大小、ETag 与记录时不一致时清理索引并返回 ErrHashNotFound
+func (d *Deduplicator) lookup(ctx context.Context, hash string, size int64) (*HashEntry, error) {
+	hash, err := normalizeHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := d.index.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	info, err := d.uploader.StatCtx(ctx, entry.ObjectKey)
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return nil, err
+	}
+	// 对象已被删除或覆盖，索引失效
+	if err != nil || info.Size != entry.Size || info.ETag != entry.ETag {
+		_ = d.index.Delete(ctx, hash)
+		return nil, ErrHashNotFound
+	}
+	if info.Size != size {
+		return nil, ErrHashNotFound
+	}
+	return entry, nil
+}
+
+// UploadFile 直接上传并记录内容哈希。hash 不为空时先写入暂存对象，与实际内容一致后才复制到 objectKey，
+// 不一致时返回 ErrChecksumMismatch，objectKey 上已有的对象保持不变
+func (d *Deduplicator) UploadFile(ctx context.Context, objectKey, hash string, reader io.Reader) (string, error) {
+	hr := newHashReader(reader)
+	if hash == "" {
+		key, err := d.uploader.UploadFileCtx(ctx, objectKey, hr)
+		if err != nil {
+			return "", err
+		}
+		return key, d.put(ctx, hr.MD5(), key)
+	}
+
+	hash, err := normalizeHash(hash)
+	if err != nil {
+		return "", err
+	}
+	staging := d.stagingKey(objectKey)
+	if _, err = d.uploader.UploadFileCtx(ctx, staging, hr); err != nil {
+		return "", err
+	}
+	defer d.discard(ctx, staging)
+	if !strings.EqualFold(hash, hr.MD5()) {
+		return "", fmt.Errorf("%w: content hash %s, got %s", ErrChecksumMismatch, hash, hr.MD5())
+	}
+	if err := d.uploader.CopyFileCtx(ctx, staging, objectKey); err != nil {
+		return "", err
+	}
+	return objectKey, d.put(ctx, hr.MD5(), objectKey)
+}
+
+// CompleteMultipartUpload 完成分片上传并记录内容哈希。objectKey 为分片上传自己的对象键，合并结果作为暂存对象，
+// 与 hash 一致后复制到 destKey 并删除暂存对象，不一致时删除暂存对象并返回 ErrChecksumMismatch，destKey 上已有的对象保持不变。
+// 驱动没有返回对象 MD5 时会读取整个对象计算，避免客户端提交错误的哈希污染索引
+func (d *Deduplicator) CompleteMultipartUpload(ctx context.Context, objectKey, uploadID, hash string, parts []Part, destKey string) (*CompleteResult, error) {
+	if destKey == "" || destKey == objectKey {
+		return nil, fmt.Errorf("upload: invalid destination key %q", destKey)
+	}
+	// 合并前校验哈希格式，避免合并完成后才因格式错误丢弃整个上传
+	hash, err := normalizeHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	res, err := d.uploader.CompleteMultipartUploadCtx(ctx, objectKey, uploadID, parts)
+	if err != nil {
+		return nil, err
+	}
+	defer d.discard(ctx, res.ObjectKey)
+
+	sum := res.MD5
+	if sum == "" {
+		if sum, err = d.objectMD5(ctx, res.ObjectKey); err != nil {
+			return nil, err
+		}
+	}
+	if !strings.EqualFold(hash, sum) {
+		return nil, fmt.Errorf("%w: content hash %s, got %s", ErrChecksumMismatch, hash, sum)
+	}
+	if err = d.uploader.CopyFileCtx(ctx, res.ObjectKey, destKey); err != nil {
+		return nil, err
+	}
+
+	res.ObjectKey = destKey
+	res.MD5 = sum
+	if err = d.put(ctx, sum, destKey); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// normalizeHash 将内容哈希转换为小写，格式错误时返回 ErrInvalidHash
+func normalizeHash(hash string) (string, error) {
+	hash = strings.ToLower(hash)
+	if !hashPattern.MatchString(hash) {
+		return "", fmt.Errorf("%w %q", ErrInvalidHash, hash)
+	}
+	return hash, nil
+}
+
+// put 记录内容哈希对应的对象及其当前的大小与 ETag
+func (d *Deduplicator) put(ctx context.Context, hash, objectKey string) error {
+	info, err := d.uploader.StatCtx(ctx, objectKey)
+	if err != nil {
+		return fmt.Errorf("failed to index content hash: %w", err)
+	}
+	entry := &HashEntry{Hash: hash, ObjectKey: objectKey, Size: info.Size, ETag: info.ETag, CreatedAt: time.Now()}
+	if err = d.index.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to index content hash: %w", err)
+	}
+	return nil
+}
+
+// stagingKey 生成暂存对象键，保留扩展名以便通过上传策略的校验
+func (d *Deduplicator) stagingKey(objectKey string) string {
+	return dedupStagingPrefix + rand.Text() + strings.ToLower(path.Ext(objectKey))
+}
+
+// discard 删除暂存对象，ctx 已取消时仍然清理
+func (d *Deduplicator) discard(ctx context.Context, objectKey string) {
+	_ = d.uploader.DeleteFileCtx(context.WithoutCancel(ctx), objectKey)
+}
+
+// objectMD5 读取整个对象计算 MD5
+func (d *Deduplicator) objectMD5(ctx context.Context, objectKey string) (string, error) {
+	reader, err := d.uploader.OpenCtx(ctx, objectKey, nil)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := md5.New()
+	if _, err = io.Copy(hash, newCtxReader(ctx, reader)); err != nil {
+		return "", fmt.Errorf("failed to read object: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,159 @@
+package upload
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanghaidi/zero-common/config"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestHashIndex(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	if err = db.AutoMigrate(&HashEntry{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+
+	indexes := map[string]HashIndex{
+		"redis": NewRedisHashIndex(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+		"gorm":  NewGormHashIndex(db),
+	}
+	for name, index := range indexes {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			hash := "900150983cd24fb0d6963f7d28e17f72"
+			if _, err := index.Get(ctx, hash); !errors.Is(err, ErrHashNotFound) {
+				t.Fatalf("Get() error = %v, want ErrHashNotFound", err)
+			}
+			if err := index.Put(ctx, &HashEntry{Hash: hash, ObjectKey: "a.mp4", Size: 3}); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if err := index.Put(ctx, &HashEntry{Hash: hash, ObjectKey: "b.mp4", Size: 3}); err != nil {
+				t.Fatalf("Put() overwrite error = %v", err)
+			}
+			entry, err := index.Get(ctx, hash)
+			if err != nil || entry.ObjectKey != "b.mp4" || entry.Size != 3 {
+				t.Fatalf("Get() = %+v, %v", entry, err)
+			}
+			if err = index.Delete(ctx, hash); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err = index.Get(ctx, hash); !errors.Is(err, ErrHashNotFound) {
+				t.Errorf("Get() after delete error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	ctx := context.Background()
+	u := NewLocalUploader(t.TempDir())
+	mr := miniredis.RunT(t)
+	d := NewDeduplicator(u, NewRedisHashIndex(redis.NewClient(&redis.Options{Addr: mr.Addr()})))
+
+	content := "course-video"
+	sum := md5.Sum([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+	size := int64(len(content))
+
+	if _, err := d.Instant(ctx, "mine/a.mp4", "not-a-hash", 1); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("Instant() with invalid hash error = %v, want ErrInvalidHash", err)
+	}
+	if _, err := d.Instant(ctx, "mine/a.mp4", hash, size); !errors.Is(err, ErrHashNotFound) {
+		t.Fatalf("Instant() error = %v, want ErrHashNotFound", err)
+	}
+
+	// 客户端提交的哈希与内容不一致时不记录，目标对象保持不变
+	mustUpload(t, u, "dest/bad.mp4", "existing")
+	uploadID, objectKey, _ := u.InitiateMultipartUpload("bad.mp4")
+	etag, _ := u.UploadPart(objectKey, uploadID, 1, strings.NewReader("other"), 5)
+	if _, err := d.CompleteMultipartUpload(ctx, objectKey, uploadID, hash, []Part{{ETag: etag, PartNumber: 1}}, "dest/bad.mp4"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("CompleteMultipartUpload() with wrong hash error = %v", err)
+	}
+	if ok, _ := u.Exists(objectKey); ok {
+		t.Error("staging object with wrong hash should be deleted")
+	}
+	testRead(t, u, "dest/bad.mp4", "existing")
+
+	uploadID, objectKey, _ = u.InitiateMultipartUpload("video.mp4")
+	etag, _ = u.UploadPart(objectKey, uploadID, 1, strings.NewReader(content), size)
+	// 哈希格式错误时在合并前拒绝，上传可以继续完成
+	if _, err := d.CompleteMultipartUpload(ctx, objectKey, uploadID, "not-a-hash", []Part{{ETag: etag, PartNumber: 1}}, "owner/video.mp4"); !errors.Is(err, ErrInvalidHash) {
+		t.Fatalf("CompleteMultipartUpload() with invalid hash error = %v, want ErrInvalidHash", err)
+	}
+	if parts, err := u.ListParts(objectKey, uploadID); err != nil || len(parts) != 1 {
+		t.Fatalf("ListParts() after invalid hash = %+v, %v", parts, err)
+	}
+	res, err := d.CompleteMultipartUpload(ctx, objectKey, uploadID, strings.ToUpper(hash), []Part{{ETag: etag, PartNumber: 1}}, "owner/video.mp4")
+	if err != nil || res.MD5 != hash || res.ObjectKey != "owner/video.mp4" {
+		t.Fatalf("CompleteMultipartUpload() = %+v, %v", res, err)
+	}
+	if ok, _ := u.Exists(objectKey); ok {
+		t.Error("staging object should be deleted after promotion")
+	}
+
+	// 命中时复制到调用方的对象键，原对象被删除不影响复制的对象
+	res, err = d.Instant(ctx, "mine/video.mp4", hash, size)
+	if err != nil || res.ObjectKey != "mine/video.mp4" || res.MD5 != hash || res.Size != size {
+		t.Fatalf("Instant() = %+v, %v", res, err)
+	}
+	if _, err = d.Instant(ctx, "mine/other.mp4", hash, 1); !errors.Is(err, ErrHashNotFound) {
+		t.Errorf("Instant() with other size error = %v", err)
+	}
+	if err = u.DeleteFile("owner/video.mp4"); err != nil {
+		t.Fatal(err)
+	}
+	testRead(t, u, "mine/video.mp4", content)
+
+	// 对象被删除后索引失效
+	if _, err = d.Instant(ctx, "mine/again.mp4", hash, size); !errors.Is(err, ErrHashNotFound) {
+		t.Fatalf("Instant() after delete error = %v", err)
+	}
+	if mr.Exists(config.RedisUploadHashPrefix + hash) {
+		t.Error("stale index should be deleted")
+	}
+
+	if _, err = d.UploadFile(ctx, "direct/video.mp4", hash, strings.NewReader(content)); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if res, err = d.Instant(ctx, "mine/direct.mp4", hash, size); err != nil || res.ObjectKey != "mine/direct.mp4" {
+		t.Errorf("Instant() after UploadFile = %+v, %v", res, err)
+	}
+
+	// 内容与哈希不一致时已有对象保持不变
+	if _, err = d.UploadFile(ctx, "direct/video.mp4", hash, strings.NewReader("other")); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("UploadFile() with wrong hash error = %v", err)
+	}
+	testRead(t, u, "direct/video.mp4", content)
+	if files, _ := u.ListFiles(dedupStagingPrefix); len(files) != 0 {
+		t.Errorf("staging objects left: %v", files)
+	}
+
+	// 对象被相同大小的其他内容覆盖后索引失效
+	mustUpload(t, u, "direct/video.mp4", strings.Repeat("x", len(content)))
+	if _, err = d.Instant(ctx, "mine/overwritten.mp4", hash, size); !errors.Is(err, ErrHashNotFound) {
+		t.Errorf("Instant() after overwrite error = %v, want ErrHashNotFound", err)
+	}
+	if mr.Exists(config.RedisUploadHashPrefix + hash) {
+		t.Error("overwritten index should be deleted")
+	}
+}
+
+func mustUpload(t *testing.T, u Uploader, key, content string) {
+	t.Helper()
+	if _, err := u.UploadFile(key, strings.NewReader(content)); err != nil {
+		t.Fatalf("UploadFile(%q) error = %v", key, err)
+	}
+}
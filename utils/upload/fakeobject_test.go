@@ -0,0 +1,301 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeObjectServer 是 S3 XML 协议的内存实现，用于测试 S3 与 COS 驱动
+type fakeObjectServer struct {
+	mu       sync.Mutex
+	prefix   string // 存储桶根路径，路径风格时为 /<bucket>
+	maxKeys  int    // 单页返回的最大对象数，用于测试分页
+	objects  map[string][]byte
+	uploads  map[string]*fakeUpload
+	uploadID int
+	// copySourceKey 从复制请求的源对象标识中解析出对象键
+	copySourceKey func(source string) string
+}
+
+type fakeUpload struct {
+	key       string
+	parts     map[int][]byte
+	initiated time.Time
+}
+
+func newFakeObjectServer(prefix string, copySourceKey func(string) string) *fakeObjectServer {
+	return &fakeObjectServer{
+		prefix:        prefix,
+		maxKeys:       2,
+		objects:       make(map[string][]byte),
+		uploads:       make(map[string]*fakeUpload),
+		copySourceKey: copySourceKey,
+	}
+}
+
+func (f *fakeObjectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, f.prefix), "/")
+	query := r.URL.Query()
+	if r.Header.Get("Authorization") == "" && !query.Has("X-Amz-Signature") && !query.Has("q-signature") {
+		f.error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case key == "" && r.Method == http.MethodGet && query.Has("uploads"):
+		f.listUploads(w)
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, query)
+	case key == "" && r.Method == http.MethodPost && query.Has("delete"):
+		var req deleteRequest
+		_ = xml.Unmarshal(body, &req)
+		for _, obj := range req.Objects {
+			delete(f.objects, obj.Key)
+		}
+		f.xml(w, deleteResult{})
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.uploadID++
+		id := strconv.Itoa(f.uploadID)
+		f.uploads[id] = &fakeUpload{key: key, parts: make(map[int][]byte), initiated: time.Now().UTC()}
+		f.xml(w, initiateMultipartUploadResult{UploadId: id})
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		upload, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		if contentMD5 := r.Header.Get("Content-MD5"); contentMD5 != "" {
+			sum := md5.Sum(body)
+			if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+				f.error(w, http.StatusBadRequest, "BadDigest")
+				return
+			}
+		}
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		if source := f.copySource(r); source != "" {
+			src, ok := f.objects[f.copySourceKey(source)]
+			if !ok {
+				f.error(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			var start, end int
+			rangeHeader := r.Header.Get("X-Amz-Copy-Source-Range") + r.Header.Get("X-Cos-Copy-Source-Range")
+			if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); err != nil || end >= len(src) {
+				f.error(w, http.StatusBadRequest, "InvalidArgument")
+				return
+			}
+			upload.parts[n] = src[start : end+1]
+			fmt.Fprintf(w, "<CopyPartResult><ETag>%s</ETag></CopyPartResult>", etagOf(upload.parts[n]))
+			return
+		}
+		upload.parts[n] = body
+		w.Header().Set("ETag", etagOf(body))
+	case r.Method == http.MethodGet && query.Has("uploadId"):
+		f.listParts(w, key, query)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.complete(w, key, query.Get("uploadId"), body)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && f.copySource(r) != "":
+		src, ok := f.objects[f.copySourceKey(f.copySource(r))]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		f.objects[key] = src
+		fmt.Fprintf(w, "<CopyObjectResult><ETag>%s</ETag></CopyObjectResult>", etagOf(src))
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		w.Header().Set("ETag", etagOf(body))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", etagOf(data))
+		http.ServeContent(w, r, key, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), bytes.NewReader(data))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeObjectServer) copySource(r *http.Request) string {
+	if v := r.Header.Get("X-Amz-Copy-Source"); v != "" {
+		return v
+	}
+	return r.Header.Get("X-Cos-Copy-Source")
+}
+
+func (f *fakeObjectServer) complete(w http.ResponseWriter, key, uploadID string, body []byte) {
+	upload, ok := f.uploads[uploadID]
+	if !ok || upload.key != key {
+		f.error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	var req completeMultipartUpload
+	if err := xml.Unmarshal(body, &req); err != nil {
+		f.error(w, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, part := range req.Parts {
+		data, ok := upload.parts[part.PartNumber]
+		if !ok || etagOf(data) != part.ETag {
+			// 与 S3 一致，在 200 响应中返回错误
+			f.xml(w, errorResponse{XMLName: xml.Name{Local: "Error"}, Code: "InvalidPart"})
+			return
+		}
+		buf.Write(data)
+	}
+
+	f.objects[key] = buf.Bytes()
+	delete(f.uploads, uploadID)
+	fmt.Fprintf(w, "<CompleteMultipartUploadResult><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>", key, etagOf(buf.Bytes()))
+}
+
+func (f *fakeObjectServer) listUploads(w http.ResponseWriter) {
+	var res listMultipartUploadsResult
+	for id, upload := range f.uploads {
+		res.Uploads = append(res.Uploads, struct {
+			Key       string
+			UploadId  string
+			Initiated time.Time
+		}{Key: upload.key, UploadId: id, Initiated: upload.initiated})
+	}
+	f.xml(w, struct {
+		XMLName xml.Name `xml:"ListMultipartUploadsResult"`
+		listMultipartUploadsResult
+	}{listMultipartUploadsResult: res})
+}
+
+func (f *fakeObjectServer) listParts(w http.ResponseWriter, key string, query url.Values) {
+	upload, ok := f.uploads[query.Get("uploadId")]
+	if !ok || upload.key != key {
+		f.error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	marker, _ := strconv.Atoi(query.Get("part-number-marker"))
+
+	numbers := make([]int, 0, len(upload.parts))
+	for n := range upload.parts {
+		if n > marker {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	var res listPartsResult
+	for i, n := range numbers {
+		if i == f.maxKeys {
+			res.IsTruncated = true
+			res.NextPartNumberMarker = numbers[i-1]
+			break
+		}
+		res.Parts = append(res.Parts, struct {
+			PartNumber   int
+			ETag         string
+			Size         int64
+			LastModified time.Time
+		}{PartNumber: n, ETag: etagOf(upload.parts[n]), Size: int64(len(upload.parts[n])), LastModified: time.Now().UTC()})
+	}
+	f.xml(w, struct {
+		XMLName xml.Name `xml:"ListPartsResult"`
+		listPartsResult
+	}{listPartsResult: res})
+}
+
+func (f *fakeObjectServer) list(w http.ResponseWriter, query url.Values) {
+	prefix, marker, delimiter := query.Get("prefix"), query.Get("marker"), query.Get("delimiter")
+	maxKeys := f.maxKeys
+	if v, err := strconv.Atoi(query.Get("max-keys")); err == nil && v > 0 && v < maxKeys {
+		maxKeys = v
+	}
+
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var res listBucketResult
+	seen := make(map[string]bool)
+	count := 0
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || key <= marker {
+			continue
+		}
+		entry := key
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				entry = key[:len(prefix)+i+len(delimiter)]
+				if entry <= marker || seen[entry] {
+					continue
+				}
+			}
+		}
+		if count == maxKeys {
+			res.IsTruncated = true
+			break
+		}
+		count++
+
+		if entry != key {
+			seen[entry] = true
+			res.CommonPrefixes = append(res.CommonPrefixes, struct{ Prefix string }{Prefix: entry})
+			if delimiter != "" {
+				res.NextMarker = entry
+			}
+			continue
+		}
+		res.Contents = append(res.Contents, objectSummary{Key: key, Size: int64(len(f.objects[key])), ETag: etagOf(f.objects[key]), LastModified: time.Now().UTC()})
+		if delimiter != "" {
+			res.NextMarker = key
+		}
+	}
+	if !res.IsTruncated {
+		res.NextMarker = ""
+	}
+
+	f.xml(w, struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		listBucketResult
+	}{listBucketResult: res})
+}
+
+func (f *fakeObjectServer) xml(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeObjectServer) error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
@@ -0,0 +1,370 @@
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest"
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"github.com/zhanghaidi/zero-common/config"
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+	"github.com/zhanghaidi/zero-common/utils/response"
+)
+
+// 上传接口错误码
+const (
+	CodeUploadBadRequest = 40000
+	CodeChecksumMismatch = 40001
+	CodeUploadNotFound   = 40400
+)
+
+var (
+	ErrUploadFileMissing  = errorx.NewCodeError(CodeUploadBadRequest, "缺少上传文件")
+	ErrUploadNotFound     = errorx.NewCodeError(CodeUploadNotFound, "上传任务不存在或已结束")
+	ErrUploadChecksum     = errorx.NewCodeError(CodeChecksumMismatch, "文件校验失败，请重新上传")
+	ErrUploadHash         = errorx.NewCodeError(CodeUploadBadRequest, "无效的文件哈希")
+	ErrUploadPartOrder    = errorx.NewCodeError(CodeUploadBadRequest, "分片序号重复或未按顺序排列")
+	ErrUploadPath         = errorx.NewCodeError(CodeUploadBadRequest, "无效的文件路径")
+	ErrUploadFileNotFound = errorx.NewCodeError(CodeUploadNotFound, "文件不存在")
+	ErrUploadFailed       = errorx.NewDefaultError("上传失败，请稍后重试")
+)
+
+// defaultKeyPrefix 简单上传生成对象键使用的默认前缀
+const defaultKeyPrefix = "uploads"
+
+type (
+	// FileResp 上传完成后返回的文件信息
+	FileResp struct {
+		ObjectKey string `json:"objectKey"`
+		Size      int64  `json:"size"`
+		ETag      string `json:"etag,omitempty"`
+		MD5       string `json:"md5,omitempty"`
+		URL       string `json:"url,omitempty"`
+		Instant   bool   `json:"instant,omitempty"` // 是否通过秒传完成
+	}
+
+	ChunkInitReq struct {
+		Filename  string `json:"filename"`
+		Size      int64  `json:"size,optional"`
+		PartCount int    `json:"partCount,optional"`
+		Hash      string `json:"hash,optional"` // 文件的 MD5，配置秒传时用于查找已有文件
+	}
+
+	ChunkInitResp struct {
+		UploadID  string    `json:"uploadId,omitempty"`
+		ObjectKey string    `json:"objectKey"`
+		File      *FileResp `json:"file,omitempty"` // 秒传命中时直接返回文件信息，无需继续上传
+	}
+
+	ChunkAbortReq struct {
+		ObjectKey string `json:"objectKey"`
+		UploadID  string `json:"uploadId"`
+	}
+
+	ChunkStatusReq struct {
+		ObjectKey string `form:"objectKey"`
+		UploadID  string `form:"uploadId"`
+	}
+
+	ChunkPartReq struct {
+		ObjectKey  string `form:"objectKey"`
+		UploadID   string `form:"uploadId"`
+		PartNumber int    `form:"partNumber,range=[1:10000]"`
+	}
+
+	ChunkPartResp struct {
+		PartNumber int    `json:"partNumber"`
+		ETag       string `json:"etag"`
+	}
+
+	ChunkCompleteReq struct {
+		ObjectKey string `json:"objectKey"`
+		UploadID  string `json:"uploadId"`
+		Parts     []struct {
+			PartNumber int    `json:"partNumber"`
+			ETag       string `json:"etag"`
+		} `json:"parts"`
+		Hash string `json:"hash,optional"` // 文件的 MD5，配置秒传时校验后记录
+	}
+
+	ChunkStatusResp struct {
+		UploadID  string     `json:"uploadId"`
+		ObjectKey string     `json:"objectKey"`
+		Parts     []PartInfo `json:"parts"`
+	}
+)
+
+// HandlerOption 上传接口的可选配置
+type HandlerOption func(*Handlers)
+
+// WithKeyPrefix 设置简单上传生成对象键的前缀，默认为 uploads
+func WithKeyPrefix(prefix string) HandlerOption {
+	return func(h *Handlers) {
+		h.keyPrefix = strings.Trim(prefix, "/")
+	}
+}
+
+// WithHashIndex 开启秒传，分片上传初始化时按文件哈希查找已有文件
+func WithHashIndex(index HashIndex) HandlerOption {
+	return func(h *Handlers) {
+		h.dedup = NewDeduplicator(h.uploader, index)
+	}
+}
+
+// Handlers 提供简单上传与分片上传的 go-zero 路由，统一通过 response.Response 返回
+type Handlers struct {
+	uploader  Uploader
+	dedup     *Deduplicator
+	keyPrefix string
+	now       func() time.Time
+}
+
+// NewHandlers 根据存储配置创建上传接口，上传器会按 c.Policy 校验文件
+func NewHandlers(c config.StorageConf, opts ...HandlerOption) (*Handlers, error) {
+	u, err := NewUploader(c)
+	if err != nil {
+		return nil, err
+	}
+	return NewHandlersWithUploader(NewPolicyUploader(u, c.Policy), opts...), nil
+}
+
+// NewHandlersWithUploader 使用已有的上传器创建上传接口
+func NewHandlersWithUploader(u Uploader, opts ...HandlerOption) *Handlers {
+	h := &Handlers{uploader: u, keyPrefix: defaultKeyPrefix, now: time.Now}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Routes 返回上传接口的路由，通过 server.AddRoutes(h.Routes(), rest.WithPrefix("/upload")) 注册
+func (h *Handlers) Routes() []rest.Route {
+	return []rest.Route{
+		{Method: http.MethodPost, Path: "/file", Handler: h.UploadFileHandler},
+		{Method: http.MethodPost, Path: "/chunk/init", Handler: h.ChunkInitHandler},
+		{Method: http.MethodPut, Path: "/chunk/part", Handler: h.ChunkPartHandler},
+		{Method: http.MethodPost, Path: "/chunk/complete", Handler: h.ChunkCompleteHandler},
+		{Method: http.MethodPost, Path: "/chunk/abort", Handler: h.ChunkAbortHandler},
+		{Method: http.MethodGet, Path: "/chunk/status", Handler: h.ChunkStatusHandler},
+	}
+}
+
+// UploadFileHandler 处理 multipart/form-data 简单上传，文件字段为 file，以流的方式写入存储，配置秒传时记录文件哈希
+func (h *Handlers) UploadFileHandler(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		response.Response(w, nil, ErrUploadFileMissing)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			response.Response(w, nil, ErrUploadFileMissing)
+			return
+		}
+		if err != nil {
+			response.Response(w, nil, errorx.NewCodeError(CodeUploadBadRequest, err.Error()))
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		objectKey := h.objectKey(part.FileName())
+		counter := &countingReader{reader: part}
+		if h.dedup != nil {
+			_, err = h.dedup.UploadFile(r.Context(), objectKey, "", counter)
+		} else {
+			_, err = h.uploader.UploadFileCtx(r.Context(), objectKey, counter)
+		}
+		part.Close()
+		if err != nil {
+			response.Response(w, nil, uploadError(r.Context(), err))
+			return
+		}
+		response.Response(w, h.fileResp(&CompleteResult{ObjectKey: objectKey, Size: counter.n}), nil)
+		return
+	}
+}
+
+// ChunkInitHandler 初始化分片上传，配置秒传且哈希命中时将已有文件复制到新的对象键并直接返回
+func (h *Handlers) ChunkInitHandler(w http.ResponseWriter, r *http.Request) {
+	var req ChunkInitReq
+	if err := httpx.Parse(r, &req); err != nil {
+		response.Response(w, nil, errorx.NewCodeError(CodeUploadBadRequest, err.Error()))
+		return
+	}
+
+	if h.dedup != nil && req.Hash != "" {
+		res, err := h.dedup.Instant(r.Context(), h.objectKey(req.Filename), req.Hash, req.Size)
+		if err == nil {
+			file := h.fileResp(res)
+			file.Instant = true
+			response.Response(w, &ChunkInitResp{ObjectKey: res.ObjectKey, File: file}, nil)
+			return
+		}
+		if !errors.Is(err, ErrHashNotFound) {
+			response.Response(w, nil, uploadError(r.Context(), err))
+			return
+		}
+	}
+
+	var opts []MultipartOption
+	if req.Size > 0 {
+		opts = append(opts, WithObjectSize(req.Size))
+	}
+	if req.PartCount > 0 {
+		opts = append(opts, WithPartCount(req.PartCount))
+	}
+	uploadID, objectKey, err := h.uploader.InitiateMultipartUploadCtx(r.Context(), req.Filename, opts...)
+	if err != nil {
+		response.Response(w, nil, uploadError(r.Context(), err))
+		return
+	}
+	response.Response(w, &ChunkInitResp{UploadID: uploadID, ObjectKey: objectKey}, nil)
+}
+
+// ChunkPartHandler 上传单个分片，分片内容为请求体，请求头 Content-MD5 存在时校验分片内容
+func (h *Handlers) ChunkPartHandler(w http.ResponseWriter, r *http.Request) {
+	var req ChunkPartReq
+	if err := httpx.ParseForm(r, &req); err != nil {
+		response.Response(w, nil, errorx.NewCodeError(CodeUploadBadRequest, err.Error()))
+		return
+	}
+	if r.ContentLength <= 0 {
+		response.Response(w, nil, errorx.NewCodeError(CodeUploadBadRequest, "缺少 Content-Length"))
+		return
+	}
+
+	var opts []PartOption
+	if contentMD5 := r.Header.Get("Content-MD5"); contentMD5 != "" {
+		opts = append(opts, WithContentMD5(contentMD5))
+	}
+	etag, err := h.uploader.UploadPartCtx(r.Context(), req.ObjectKey, req.UploadID, req.PartNumber, r.Body, r.ContentLength, opts...)
+	if err != nil {
+		response.Response(w, nil, uploadError(r.Context(), err))
+		return
+	}
+	response.Response(w, &ChunkPartResp{PartNumber: req.PartNumber, ETag: etag}, nil)
+}
+
+// ChunkCompleteHandler 完成分片上传，配置秒传且提交了哈希时校验后移动到新的对象键并记录
+func (h *Handlers) ChunkCompleteHandler(w http.ResponseWriter, r *http.Request) {
+	var req ChunkCompleteReq
+	if err := httpx.Parse(r, &req); err != nil {
+		response.Response(w, nil, errorx.NewCodeError(CodeUploadBadRequest, err.Error()))
+		return
+	}
+
+	parts := make([]Part, 0, len(req.Parts))
+	for _, part := range req.Parts {
+		parts = append(parts, Part{ETag: part.ETag, PartNumber: part.PartNumber})
+	}
+
+	var (
+		res *CompleteResult
+		err error
+	)
+	if h.dedup != nil && req.Hash != "" {
+		res, err = h.dedup.CompleteMultipartUpload(r.Context(), req.ObjectKey, req.UploadID, req.Hash, parts, h.objectKey(req.ObjectKey))
+	} else {
+		res, err = h.uploader.CompleteMultipartUploadCtx(r.Context(), req.ObjectKey, req.UploadID, parts)
+	}
+	if err != nil {
+		response.Response(w, nil, uploadError(r.Context(), err))
+		return
+	}
+	response.Response(w, h.fileResp(res), nil)
+}
+
+// ChunkAbortHandler 取消分片上传
+func (h *Handlers) ChunkAbortHandler(w http.ResponseWriter, r *http.Request) {
+	var req ChunkAbortReq
+	if err := httpx.Parse(r, &req); err != nil {
+		response.Response(w, nil, errorx.NewCodeError(CodeUploadBadRequest, err.Error()))
+		return
+	}
+	if err := h.uploader.AbortMultipartUploadCtx(r.Context(), req.ObjectKey, req.UploadID); err != nil {
+		response.Response(w, nil, uploadError(r.Context(), err))
+		return
+	}
+	response.Response(w, nil, nil)
+}
+
+// ChunkStatusHandler 查询已上传的分片，用于断点续传
+func (h *Handlers) ChunkStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var req ChunkStatusReq
+	if err := httpx.ParseForm(r, &req); err != nil {
+		response.Response(w, nil, errorx.NewCodeError(CodeUploadBadRequest, err.Error()))
+		return
+	}
+	parts, err := h.uploader.ListPartsCtx(r.Context(), req.ObjectKey, req.UploadID)
+	if err != nil {
+		response.Response(w, nil, uploadError(r.Context(), err))
+		return
+	}
+	if parts == nil {
+		parts = []PartInfo{}
+	}
+	response.Response(w, &ChunkStatusResp{UploadID: req.UploadID, ObjectKey: req.ObjectKey, Parts: parts}, nil)
+}
+
+// objectKey 生成简单上传的对象键：<prefix>/<yyyy>/<mm>/<dd>/<随机值><扩展名>
+func (h *Handlers) objectKey(filename string) string {
+	return path.Join(h.keyPrefix, h.now().Format("2006/01/02"), rand.Text()+strings.ToLower(path.Ext(filename)))
+}
+
+// fileResp 补充文件访问地址，驱动无法生成地址时省略
+func (h *Handlers) fileResp(res *CompleteResult) *FileResp {
+	file := &FileResp{ObjectKey: res.ObjectKey, Size: res.Size, ETag: res.ETag, MD5: res.MD5}
+	if u, err := h.uploader.URL(res.ObjectKey); err == nil {
+		file.URL = u
+	}
+	return file
+}
+
+// uploadError 将上传器返回的错误转换为 errorx.CodeError，已是 CodeError 的错误保持不变，
+// 其他错误可能包含存储服务的内部信息，记录日志后返回 ErrUploadFailed
+func uploadError(ctx context.Context, err error) error {
+	var codeErr *errorx.CodeError
+	switch {
+	case errors.As(err, &codeErr):
+		return codeErr
+	case errors.Is(err, ErrChecksumMismatch):
+		// 驱动取消上传时错误同时匹配 ErrSessionNotFound，优先提示校验失败
+		return ErrUploadChecksum
+	case errors.Is(err, ErrSessionNotFound):
+		return ErrUploadNotFound
+	case errors.Is(err, ErrInvalidHash):
+		return ErrUploadHash
+	case errors.Is(err, ErrInvalidPartOrder):
+		return ErrUploadPartOrder
+	case errors.Is(err, ErrInvalidPath):
+		return ErrUploadPath
+	case errors.Is(err, ErrObjectNotFound):
+		return ErrUploadFileNotFound
+	default:
+		logx.WithContext(ctx).Errorw("upload failed", logx.Field("detail", err))
+		return ErrUploadFailed
+	}
+}
+
+// countingReader 统计读取的字节数
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
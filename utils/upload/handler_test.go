@@ -0,0 +1,222 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanghaidi/zero-common/config"
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+)
+
+// handlerBody 与 response.Body 结构一致，Data 延迟解析
+type handlerBody struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+func newTestHandlers(t *testing.T, opts ...HandlerOption) (*Handlers, http.Handler) {
+	var c config.StorageConf
+	c.Driver = "local"
+	c.Local.Directory = t.TempDir()
+	c.Local.BaseUrl = "http://localhost/files"
+	c.Policy.AllowedExts = []string{".mp4", ".txt"}
+
+	h, err := NewHandlers(c, opts...)
+	if err != nil {
+		t.Fatalf("NewHandlers() error = %v", err)
+	}
+	h.now = func() time.Time { return time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC) }
+
+	mux := http.NewServeMux()
+	for _, route := range h.Routes() {
+		mux.HandleFunc(route.Method+" "+route.Path, route.Handler)
+	}
+	return h, mux
+}
+
+func serveJSON(t *testing.T, handler http.Handler, req *http.Request, data any) handlerBody {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body handlerBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s response = %s", req.Method, req.URL, rec.Body.String())
+	}
+	if data != nil && body.Code == 0 {
+		if err := json.Unmarshal(body.Data, data); err != nil {
+			t.Fatalf("unmarshal data %s error = %v", body.Data, err)
+		}
+	}
+	return body
+}
+
+func postJSON(path string, v any) *http.Request {
+	data, _ := json.Marshal(v)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestUploadFileHandler(t *testing.T) {
+	h, handler := newTestHandlers(t)
+
+	newRequest := func(filename, content string) *http.Request {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		_ = mw.WriteField("name", "ignored")
+		fw, _ := mw.CreateFormFile("file", filename)
+		fw.Write([]byte(content))
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/file", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+
+	var file FileResp
+	if body := serveJSON(t, handler, newRequest("Note.TXT", "hello"), &file); body.Code != 0 {
+		t.Fatalf("upload response = %+v", body)
+	}
+	if !strings.HasPrefix(file.ObjectKey, "uploads/2024/05/06/") || !strings.HasSuffix(file.ObjectKey, ".txt") || file.Size != 5 {
+		t.Errorf("upload file = %+v", file)
+	}
+	if file.URL != "http://localhost/files/"+file.ObjectKey {
+		t.Errorf("upload url = %s", file.URL)
+	}
+	testRead(t, h.uploader, file.ObjectKey, "hello")
+
+	if body := serveJSON(t, handler, newRequest("a.exe", "MZ"), nil); body.Code != CodeExtensionNotAllowed {
+		t.Errorf("upload exe response = %+v", body)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/file", strings.NewReader("{}"))
+	if body := serveJSON(t, handler, req, nil); body.Code != CodeUploadBadRequest {
+		t.Errorf("upload without file response = %+v", body)
+	}
+}
+
+func TestChunkHandlers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	h, handler := newTestHandlers(t, WithHashIndex(NewRedisHashIndex(redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1}))))
+
+	content := []string{"chunk-1|", "chunk-2"}
+	sum := md5.Sum([]byte(strings.Join(content, "")))
+	hash := hex.EncodeToString(sum[:])
+
+	var init ChunkInitResp
+	if body := serveJSON(t, handler, postJSON("/chunk/init", ChunkInitReq{Filename: "movie.mp4", PartCount: 2, Hash: hash}), &init); body.Code != 0 || init.UploadID == "" {
+		t.Fatalf("init response = %+v, %+v", body, init)
+	}
+
+	uploadPart := func(n int, content, contentMD5 string) handlerBody {
+		req := httptest.NewRequest(http.MethodPut, "/chunk/part?objectKey="+init.ObjectKey+"&uploadId="+init.UploadID+"&partNumber="+strconv.Itoa(n), strings.NewReader(content))
+		if contentMD5 != "" {
+			req.Header.Set("Content-MD5", contentMD5)
+		}
+		return serveJSON(t, handler, req, nil)
+	}
+	if body := uploadPart(1, content[0], base64.StdEncoding.EncodeToString(make([]byte, 16))); body.Code != CodeChecksumMismatch {
+		t.Errorf("part with bad md5 response = %+v", body)
+	}
+	var etags []string
+	for i, chunk := range content {
+		sum := md5.Sum([]byte(chunk))
+		body := uploadPart(i+1, chunk, base64.StdEncoding.EncodeToString(sum[:]))
+		var part ChunkPartResp
+		if err := json.Unmarshal(body.Data, &part); body.Code != 0 || err != nil {
+			t.Fatalf("part %d response = %+v", i+1, body)
+		}
+		etags = append(etags, part.ETag)
+	}
+
+	var status ChunkStatusResp
+	req := httptest.NewRequest(http.MethodGet, "/chunk/status?objectKey="+init.ObjectKey+"&uploadId="+init.UploadID, nil)
+	if body := serveJSON(t, handler, req, &status); body.Code != 0 || len(status.Parts) != 2 {
+		t.Fatalf("status response = %+v, %+v", body, status)
+	}
+
+	complete := ChunkCompleteReq{ObjectKey: init.ObjectKey, UploadID: init.UploadID, Hash: hash}
+	for i, etag := range etags {
+		complete.Parts = append(complete.Parts, struct {
+			PartNumber int    `json:"partNumber"`
+			ETag       string `json:"etag"`
+		}{PartNumber: i + 1, ETag: etag})
+	}
+	var file FileResp
+	if body := serveJSON(t, handler, postJSON("/chunk/complete", complete), &file); body.Code != 0 || file.MD5 != hash || file.Size != 15 {
+		t.Fatalf("complete response = %+v, %+v", body, file)
+	}
+	if !strings.HasPrefix(file.ObjectKey, "uploads/2024/05/06/") || !strings.HasSuffix(file.ObjectKey, ".mp4") {
+		t.Errorf("complete object key = %s", file.ObjectKey)
+	}
+	testRead(t, h.uploader, file.ObjectKey, strings.Join(content, ""))
+
+	// 相同内容再次上传时秒传，复制到新的对象键，不返回已有文件的地址
+	var instant ChunkInitResp
+	if body := serveJSON(t, handler, postJSON("/chunk/init", ChunkInitReq{Filename: "copy.mp4", Size: 15, Hash: hash}), &instant); body.Code != 0 {
+		t.Fatalf("instant init response = %+v", body)
+	}
+	if instant.UploadID != "" || instant.File == nil || !instant.File.Instant || instant.ObjectKey == file.ObjectKey ||
+		instant.File.ObjectKey != instant.ObjectKey || instant.File.URL != "http://localhost/files/"+instant.ObjectKey {
+		t.Errorf("instant init = %+v", instant)
+	}
+	testRead(t, h.uploader, instant.ObjectKey, strings.Join(content, ""))
+
+	// 已完成的上传无法再取消
+	if body := serveJSON(t, handler, postJSON("/chunk/abort", ChunkAbortReq{ObjectKey: init.ObjectKey, UploadID: init.UploadID}), nil); body.Code != CodeUploadNotFound {
+		t.Errorf("abort response = %+v", body)
+	}
+
+	if body := serveJSON(t, handler, postJSON("/chunk/init", ChunkInitReq{Filename: "copy.mp4", Hash: "not-a-hash"}), nil); body.Code != CodeUploadBadRequest {
+		t.Errorf("invalid hash response = %+v", body)
+	}
+	// 存储服务的错误信息不返回给客户端
+	mr.Close()
+	if body := serveJSON(t, handler, postJSON("/chunk/init", ChunkInitReq{Filename: "copy.mp4", Hash: hash}), nil); body.Code != errorx.DefaultCode || body.Msg != "上传失败，请稍后重试" {
+		t.Errorf("index error response = %+v", body)
+	}
+}
+
+// missingUploader 模拟合并时对象已被删除
+type missingUploader struct {
+	Uploader
+}
+
+func (m *missingUploader) CompleteMultipartUploadCtx(ctx context.Context, objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h, handler := newTestHandlers(t)
+
+	// 客户端传入的对象键越出存储目录
+	for _, key := range []string{"../../etc/passwd", "a/../../x.mp4"} {
+		req := httptest.NewRequest(http.MethodGet, "/chunk/status?objectKey="+key+"&uploadId=ABC", nil)
+		if body := serveJSON(t, handler, req, nil); body.Code != CodeUploadBadRequest {
+			t.Errorf("status with key %q response = %+v", key, body)
+		}
+	}
+
+	h.uploader = &missingUploader{Uploader: h.uploader}
+	complete := ChunkCompleteReq{ObjectKey: "chunk-upload/a.mp4", UploadID: "ABC", Parts: []struct {
+		PartNumber int    `json:"partNumber"`
+		ETag       string `json:"etag"`
+	}{{PartNumber: 1, ETag: "etag"}}}
+	if body := serveJSON(t, handler, postJSON("/chunk/complete", complete), nil); body.Code != CodeUploadNotFound || body.Msg != ErrUploadFileNotFound.Error() {
+		t.Errorf("complete missing object response = %+v", body)
+	}
+}
@@ -0,0 +1,242 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/metric"
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+const janitorNamespace = "upload_janitor"
+
+var (
+	metricJanitorReclaimedBytes = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: janitorNamespace,
+		Name:      "reclaimed_bytes_total",
+		Help:      "upload janitor reclaimed bytes, dry_run mode counts reclaimable bytes.",
+		Labels:    []string{"mode"},
+	})
+	metricJanitorReclaimedUploads = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: janitorNamespace,
+		Name:      "reclaimed_uploads_total",
+		Help:      "upload janitor aborted multipart uploads, orphan part files and staging objects.",
+		Labels:    []string{"mode"},
+	})
+)
+
+// orphanSweeper 由会话与分片分开存储的驱动实现，清理会话已不存在的分片
+type orphanSweeper interface {
+	sweepOrphanParts(ctx context.Context, before time.Time, dryRun bool) (files int, size int64, err error)
+}
+
+// unwrapUploader 逐层解开 PolicyUploader 等实现了 Unwrap 的包装器，返回最内层的驱动
+func unwrapUploader(u Uploader) Uploader {
+	for {
+		w, ok := u.(interface{ Unwrap() Uploader })
+		if !ok {
+			return u
+		}
+		u = w.Unwrap()
+	}
+}
+
+// StaleUpload 废弃的分片上传
+type StaleUpload struct {
+	MultipartUpload
+	LastModified time.Time // 最后一个分片的上传时间，没有分片时为 Initiated
+	Parts        int
+	Size         int64
+}
+
+// JanitorReport 一次清理的结果
+type JanitorReport struct {
+	DryRun         bool
+	Uploads        []StaleUpload
+	OrphanParts    int     // 会话已不存在的本地分片文件数
+	StagingObjects int     // 秒传校验遗留的暂存对象数
+	ReclaimedBytes int64   // 回收的字节数，DryRun 时为可回收的字节数
+	Errors         []error // 单个上传清理失败不会中断整次清理
+}
+
+// Janitor 定期清理超过 MaxAge 没有新分片的分片上传与秒传遗留的暂存对象，实现 go-zero 的 service.Service
+type Janitor struct {
+	uploader Uploader
+	conf     config.JanitorConf
+	now      func() time.Time
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewJanitor 创建清理废弃分片上传的 Janitor
+func NewJanitor(uploader Uploader, conf config.JanitorConf) *Janitor {
+	return &Janitor{
+		uploader: uploader,
+		conf:     conf,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start 按 Interval 定期清理，阻塞直到 Stop 被调用
+func (j *Janitor) Start() {
+	if j.conf.Interval <= 0 {
+		logx.Errorw("upload janitor disabled", logx.Field("interval", j.conf.Interval))
+		return
+	}
+
+	ticker := time.NewTicker(j.conf.Interval)
+	defer ticker.Stop()
+	for {
+		j.runQuietly()
+		select {
+		case <-ticker.C:
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// Stop 停止定期清理
+func (j *Janitor) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+}
+
+func (j *Janitor) runQuietly() {
+	report, err := j.Run(context.Background())
+	if err != nil {
+		logx.Errorw("upload janitor failed", logx.Field("detail", err))
+		return
+	}
+	logx.Infow("upload janitor finished",
+		logx.Field("dryRun", report.DryRun),
+		logx.Field("uploads", len(report.Uploads)),
+		logx.Field("orphanParts", report.OrphanParts),
+		logx.Field("stagingObjects", report.StagingObjects),
+		logx.Field("reclaimedBytes", report.ReclaimedBytes),
+		logx.Field("errors", len(report.Errors)))
+	for _, err := range report.Errors {
+		logx.Errorw("upload janitor failed to reclaim upload", logx.Field("detail", err))
+	}
+}
+
+// Run 执行一次清理并返回报告，DryRun 时只统计不删除
+func (j *Janitor) Run(ctx context.Context) (*JanitorReport, error) {
+	if j.conf.MaxAge <= 0 {
+		return nil, fmt.Errorf("upload: invalid janitor max age %s", j.conf.MaxAge)
+	}
+	mode := "delete"
+	if j.conf.DryRun {
+		mode = "dry_run"
+	}
+
+	before := j.now().Add(-j.conf.MaxAge)
+	report := &JanitorReport{DryRun: j.conf.DryRun}
+	uploads, err := j.uploader.ListMultipartUploadsCtx(ctx)
+	if errors.Is(err, ErrListUploadsUnsupported) {
+		// 无法列举时不能当作没有废弃的上传，记录到报告中继续清理孤立分片
+		report.Errors = append(report.Errors, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, upload := range uploads {
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+		if upload.Initiated.After(before) {
+			continue
+		}
+
+		stale, err := j.inspect(ctx, upload)
+		if err != nil {
+			// 清理期间上传已完成或被取消
+			if !errors.Is(err, ErrSessionNotFound) {
+				report.Errors = append(report.Errors, err)
+			}
+			continue
+		}
+		if stale.LastModified.After(before) {
+			continue
+		}
+
+		if !j.conf.DryRun {
+			if err = j.uploader.AbortMultipartUploadCtx(ctx, upload.ObjectKey, upload.UploadID); err != nil {
+				report.Errors = append(report.Errors, fmt.Errorf("abort %s(%s): %w", upload.ObjectKey, upload.UploadID, err))
+				continue
+			}
+		}
+		report.Uploads = append(report.Uploads, *stale)
+		report.ReclaimedBytes += stale.Size
+		metricJanitorReclaimedUploads.Inc(mode)
+		metricJanitorReclaimedBytes.Add(float64(stale.Size), mode)
+	}
+
+	if sweeper, ok := unwrapUploader(j.uploader).(orphanSweeper); ok {
+		files, size, err := sweeper.sweepOrphanParts(ctx, before, j.conf.DryRun)
+		if err != nil {
+			report.Errors = append(report.Errors, err)
+		}
+		report.OrphanParts = files
+		report.ReclaimedBytes += size
+		metricJanitorReclaimedUploads.Add(float64(files), mode)
+		metricJanitorReclaimedBytes.Add(float64(size), mode)
+	}
+
+	files, size := j.sweepStaging(ctx, before, report)
+	report.StagingObjects = files
+	report.ReclaimedBytes += size
+	metricJanitorReclaimedUploads.Add(float64(files), mode)
+	metricJanitorReclaimedBytes.Add(float64(size), mode)
+	return report, nil
+}
+
+// sweepStaging 清理 Deduplicator 复制或删除失败时遗留的暂存对象，错误记录到报告中
+func (j *Janitor) sweepStaging(ctx context.Context, before time.Time, report *JanitorReport) (files int, size int64) {
+	// 先列出再删除，避免删除影响分页
+	var stale []ObjectInfo
+	for object, err := range Objects(ctx, j.uploader, ListOptions{Prefix: dedupStagingPrefix}) {
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("list staging objects: %w", err))
+			return 0, 0
+		}
+		if !object.IsDir && object.LastModified.Before(before) {
+			stale = append(stale, object)
+		}
+	}
+
+	for _, object := range stale {
+		if !j.conf.DryRun {
+			if err := j.uploader.DeleteFileCtx(ctx, object.Key); err != nil {
+				report.Errors = append(report.Errors, fmt.Errorf("delete staging object %s: %w", object.Key, err))
+				continue
+			}
+		}
+		files++
+		size += object.Size
+	}
+	return files, size
+}
+
+// inspect 统计分片上传已上传的分片
+func (j *Janitor) inspect(ctx context.Context, upload MultipartUpload) (*StaleUpload, error) {
+	parts, err := j.uploader.ListPartsCtx(ctx, upload.ObjectKey, upload.UploadID)
+	if err != nil {
+		return nil, fmt.Errorf("list parts of %s(%s): %w", upload.ObjectKey, upload.UploadID, err)
+	}
+
+	stale := &StaleUpload{MultipartUpload: upload, LastModified: upload.Initiated, Parts: len(parts)}
+	for _, part := range parts {
+		stale.Size += part.Size
+		if part.LastModified.After(stale.LastModified) {
+			stale.LastModified = part.LastModified
+		}
+	}
+	return stale, nil
+}
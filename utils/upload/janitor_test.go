@@ -0,0 +1,131 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+func TestJanitorLocal(t *testing.T) {
+	local := NewLocalUploader(t.TempDir())
+	// 包装后仍然可以清理孤立分片
+	u := NewPolicyUploader(local, config.UploadPolicyConf{})
+
+	staleID, staleKey, _ := u.InitiateMultipartUpload("stale.mp4")
+	if _, err := u.UploadPart(staleKey, staleID, 1, strings.NewReader("abc"), 3); err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+	// 会话已丢失的分片文件
+	orphan := local.partPath("ORPHAN", 1)
+	if err := os.WriteFile(orphan, []byte("orphan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	j := NewJanitor(u, config.JanitorConf{MaxAge: time.Hour, DryRun: true})
+	j.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	report, err := j.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Uploads) != 1 || report.Uploads[0].UploadID != staleID || report.OrphanParts != 1 || report.ReclaimedBytes != 9 {
+		t.Fatalf("Run() dry run report = %+v", report)
+	}
+	if _, err = u.ListParts(staleKey, staleID); err != nil {
+		t.Errorf("dry run should keep upload, ListParts() error = %v", err)
+	}
+	if _, err = os.Stat(orphan); err != nil {
+		t.Errorf("dry run should keep orphan part, error = %v", err)
+	}
+
+	// 新的上传不会被清理
+	freshID, freshKey, _ := u.InitiateMultipartUpload("fresh.mp4")
+	j.conf.DryRun = false
+	if err = local.sessions.PutPart(context.Background(), freshID, PartInfo{PartNumber: 1, LastModified: time.Now().Add(90 * time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = j.Run(context.Background())
+	if err != nil || len(report.Errors) > 0 {
+		t.Fatalf("Run() = %+v, %v", report, err)
+	}
+	if len(report.Uploads) != 1 || report.ReclaimedBytes != 9 {
+		t.Fatalf("Run() report = %+v", report)
+	}
+	if _, err = u.ListParts(staleKey, staleID); err == nil {
+		t.Error("stale upload should be aborted")
+	}
+	if _, err = os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("orphan part should be deleted, error = %v", err)
+	}
+	if _, err = u.ListParts(freshKey, freshID); err != nil {
+		t.Errorf("recently active upload should be kept, ListParts() error = %v", err)
+	}
+}
+
+func TestJanitorS3(t *testing.T) {
+	u, fake := newTestS3Uploader(t)
+
+	uploadID, objectKey, err := u.InitiateMultipartUpload("a.mp4")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	if _, err = u.UploadPart(objectKey, uploadID, 1, strings.NewReader("abcd"), 4); err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+	fake.uploads[uploadID].initiated = time.Now().Add(-48 * time.Hour)
+
+	uploads, err := u.ListMultipartUploads()
+	if err != nil || len(uploads) != 1 || uploads[0].UploadID != uploadID || uploads[0].ObjectKey != objectKey {
+		t.Fatalf("ListMultipartUploads() = %+v, %v", uploads, err)
+	}
+
+	j := NewJanitor(u, config.JanitorConf{MaxAge: time.Hour})
+	// 分片的上传时间由服务端记录为当前时间
+	j.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	report, err := j.Run(context.Background())
+	if err != nil || len(report.Uploads) != 1 || report.ReclaimedBytes != 4 {
+		t.Fatalf("Run() = %+v, %v", report, err)
+	}
+	if len(fake.uploads) != 0 {
+		t.Errorf("stale upload should be aborted, uploads = %v", fake.uploads)
+	}
+}
+
+func TestJanitorQiniu(t *testing.T) {
+	u, _ := newTestQiniuUploader(t)
+	report, err := NewJanitor(u, config.JanitorConf{MaxAge: time.Hour}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Errors) != 1 || !errors.Is(report.Errors[0], ErrListUploadsUnsupported) {
+		t.Errorf("Run() report = %+v, want ErrListUploadsUnsupported", report)
+	}
+}
+
+func TestJanitorStaging(t *testing.T) {
+	u := NewLocalUploader(t.TempDir())
+	mustUpload(t, u, dedupStagingPrefix+"stale.mp4", "stale")
+	mustUpload(t, u, "course/a.mp4", "kept-file")
+
+	j := NewJanitor(u, config.JanitorConf{MaxAge: time.Hour})
+	report, err := j.Run(context.Background())
+	if err != nil || report.StagingObjects != 0 {
+		t.Fatalf("Run() with fresh staging object = %+v, %v", report, err)
+	}
+
+	j.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	report, err = j.Run(context.Background())
+	if err != nil || len(report.Errors) > 0 || report.StagingObjects != 1 || report.ReclaimedBytes != 5 {
+		t.Fatalf("Run() = %+v, %v", report, err)
+	}
+	if ok, _ := u.Exists(dedupStagingPrefix + "stale.mp4"); ok {
+		t.Error("stale staging object should be deleted")
+	}
+	testRead(t, u, "course/a.mp4", "kept-file")
+}
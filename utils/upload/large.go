@@ -0,0 +1,218 @@
+package upload
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+)
+
+const (
+	// DefaultLargePartSize UploadLarge 默认的分片大小
+	DefaultLargePartSize = 16 * 1024 * 1024
+	// DefaultLargeConcurrency UploadLarge 默认同时上传的分片数
+	DefaultLargeConcurrency = 4
+
+	// maxLargeParts 分片数上限，与 S3、OSS 一致，超出时自动增大分片
+	maxLargeParts = 10000
+	// maxLargeBackoff 重试间隔上限
+	maxLargeBackoff = 30 * time.Second
+)
+
+// Progress UploadLarge 的上传进度
+type Progress struct {
+	ObjectKey      string
+	UploadedBytes  int64
+	TotalBytes     int64
+	CompletedParts int
+	TotalParts     int
+}
+
+type largeOptions struct {
+	partSize    int64
+	concurrency int
+	retries     int
+	backoff     time.Duration
+	progress    func(Progress)
+}
+
+// LargeOption 定义 UploadLarge 的参数
+type LargeOption func(*largeOptions)
+
+// WithPartSize 设置分片大小，S3 与 OSS 要求除最后一个分片外不小于 5MB
+func WithPartSize(size int64) LargeOption {
+	return func(o *largeOptions) {
+		o.partSize = size
+	}
+}
+
+// WithConcurrency 设置同时上传的分片数
+func WithConcurrency(n int) LargeOption {
+	return func(o *largeOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithRetry 设置分片失败后的重试次数，第 n 次重试前等待 backoff*2^(n-1)，最长 30 秒
+func WithRetry(retries int, backoff time.Duration) LargeOption {
+	return func(o *largeOptions) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// WithProgress 设置进度回调，每个分片完成后调用一次，回调不会并发执行
+func WithProgress(fn func(Progress)) LargeOption {
+	return func(o *largeOptions) {
+		o.progress = fn
+	}
+}
+
+func newLargeOptions(opts []LargeOption) largeOptions {
+	o := largeOptions{
+		partSize:    DefaultLargePartSize,
+		concurrency: DefaultLargeConcurrency,
+		retries:     3,
+		backoff:     time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.partSize <= 0 {
+		o.partSize = DefaultLargePartSize
+	}
+	if o.concurrency <= 0 {
+		o.concurrency = 1
+	}
+	return o
+}
+
+// UploadLarge 将 reader 中 size 字节按分片并发上传到 u 的 objectKey，全部分片成功后合并，任一分片重试后仍失败或 ctx 取消时取消整个上传
+func UploadLarge(ctx context.Context, u Uploader, objectKey string, reader io.ReaderAt, size int64, opts ...LargeOption) (*CompleteResult, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("upload: invalid size %d", size)
+	}
+	o := newLargeOptions(opts)
+	partSize := o.partSize
+	if (size+partSize-1)/partSize > maxLargeParts {
+		partSize = (size + maxLargeParts - 1) / maxLargeParts
+	}
+	count := max(int((size+partSize-1)/partSize), 1)
+
+	uploadID, objectKey, err := u.InitiateMultipartUploadCtx(ctx, objectKey, WithObjectKey(objectKey), WithObjectSize(size), WithPartCount(count))
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := uploadParts(ctx, u, objectKey, uploadID, reader, size, partSize, count, o)
+	if err == nil {
+		var res *CompleteResult
+		if res, err = u.CompleteMultipartUploadCtx(ctx, objectKey, uploadID, parts); err == nil {
+			return res, nil
+		}
+	}
+
+	// ctx 已取消时仍需清理已上传的分片
+	if abortErr := u.AbortMultipartUploadCtx(context.WithoutCancel(ctx), objectKey, uploadID); abortErr != nil {
+		return nil, fmt.Errorf("%w (abort multipart upload: %v)", err, abortErr)
+	}
+	return nil, err
+}
+
+// uploadParts 使用 o.concurrency 个 worker 上传所有分片，返回按分片号排序的结果，第一个错误会停止其余分片
+func uploadParts(ctx context.Context, u Uploader, objectKey, uploadID string, reader io.ReaderAt, size, partSize int64, count int, o largeOptions) ([]Part, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		parts    = make([]Part, count)
+		jobs     = make(chan int)
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		progress = Progress{ObjectKey: objectKey, TotalBytes: size, TotalParts: count}
+	)
+	for range min(o.concurrency, count) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				offset := int64(i) * partSize
+				n := min(partSize, size-offset)
+				etag, err := uploadPartWithRetry(ctx, u, objectKey, uploadID, i+1, io.NewSectionReader(reader, offset, n), n, o)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					parts[i] = Part{PartNumber: i + 1, ETag: etag}
+					progress.UploadedBytes += n
+					progress.CompletedParts++
+					if o.progress != nil {
+						o.progress(progress)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range count {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// uploadPartWithRetry 上传单个分片并携带 Content-MD5，失败时按指数退避重试，ctx 取消与策略校验失败不重试
+func uploadPartWithRetry(ctx context.Context, u Uploader, objectKey, uploadID string, partNumber int, section *io.SectionReader, n int64, o largeOptions) (string, error) {
+	hash := md5.New()
+	if _, err := io.Copy(hash, section); err != nil {
+		return "", fmt.Errorf("failed to read part %d: %w", partNumber, err)
+	}
+	contentMD5 := base64.StdEncoding.EncodeToString(hash.Sum(nil))
+
+	backoff := o.backoff
+	for attempt := 0; ; attempt++ {
+		if _, err := section.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		etag, err := u.UploadPartCtx(ctx, objectKey, uploadID, partNumber, section, n, WithContentMD5(contentMD5))
+		if err == nil {
+			return etag, nil
+		}
+		var codeErr *errorx.CodeError
+		if attempt >= o.retries || ctx.Err() != nil || errors.As(err, &codeErr) || errors.Is(err, ErrSessionNotFound) {
+			return "", fmt.Errorf("upload part %d: %w", partNumber, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxLargeBackoff)
+	}
+}
@@ -0,0 +1,139 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyUploader 指定分片的前 failures 次上传返回错误，并记录同时上传的分片数
+type flakyUploader struct {
+	Uploader
+	failPart int
+	failures int32
+
+	mu       sync.Mutex
+	attempts map[int]int
+	active   atomic.Int32
+	peak     atomic.Int32
+	aborted  atomic.Bool
+}
+
+func (f *flakyUploader) UploadPartCtx(ctx context.Context, objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	active := f.active.Add(1)
+	defer f.active.Add(-1)
+	for peak := f.peak.Load(); active > peak && !f.peak.CompareAndSwap(peak, active); peak = f.peak.Load() {
+	}
+	time.Sleep(time.Millisecond)
+
+	f.mu.Lock()
+	f.attempts[partNumber]++
+	attempt := f.attempts[partNumber]
+	f.mu.Unlock()
+	if partNumber == f.failPart && (f.failures < 0 || attempt <= int(f.failures)) {
+		return "", errors.New("connection reset")
+	}
+	return f.Uploader.UploadPartCtx(ctx, objectKey, uploadID, partNumber, reader, partSize, opts...)
+}
+
+func (f *flakyUploader) AbortMultipartUploadCtx(ctx context.Context, objectKey, uploadID string) error {
+	f.aborted.Store(true)
+	return f.Uploader.AbortMultipartUploadCtx(ctx, objectKey, uploadID)
+}
+
+func TestUploadLarge(t *testing.T) {
+	data := make([]byte, 1<<20+123)
+	for i := range data {
+		data[i] = byte(rand.IntN(256))
+	}
+	sum := md5.Sum(data)
+
+	t.Run("retry", func(t *testing.T) {
+		dir := t.TempDir()
+		u := &flakyUploader{Uploader: NewLocalUploader(dir), failPart: 3, failures: 2, attempts: map[int]int{}}
+
+		var last Progress
+		calls := 0
+		res, err := UploadLarge(context.Background(), u, "backup/big.bin", bytes.NewReader(data), int64(len(data)),
+			WithPartSize(64*1024), WithConcurrency(4), WithRetry(3, time.Millisecond),
+			WithProgress(func(p Progress) {
+				calls++
+				if p.UploadedBytes < last.UploadedBytes {
+					t.Errorf("progress went backwards: %+v after %+v", p, last)
+				}
+				last = p
+			}))
+		if err != nil {
+			t.Fatalf("UploadLarge() error = %v", err)
+		}
+		if res.ObjectKey != "backup/big.bin" || res.MD5 != hex.EncodeToString(sum[:]) || res.Size != int64(len(data)) {
+			t.Errorf("UploadLarge() = %+v", res)
+		}
+		if got, _ := os.ReadFile(filepath.Join(dir, res.ObjectKey)); !bytes.Equal(got, data) {
+			t.Error("uploaded content mismatch")
+		}
+		if calls != 17 || last.UploadedBytes != int64(len(data)) || last.CompletedParts != 17 || last.TotalParts != 17 {
+			t.Errorf("progress calls = %d, last = %+v", calls, last)
+		}
+		if u.attempts[3] != 3 {
+			t.Errorf("part 3 attempts = %d, want 3", u.attempts[3])
+		}
+		if peak := u.peak.Load(); peak < 2 || peak > 4 {
+			t.Errorf("peak concurrency = %d, want 2..4", peak)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		u := &flakyUploader{Uploader: NewLocalUploader(t.TempDir()), failPart: 2, failures: -1, attempts: map[int]int{}}
+		_, err := UploadLarge(context.Background(), u, "backup/big.bin", bytes.NewReader(data), int64(len(data)),
+			WithPartSize(64*1024), WithRetry(1, time.Millisecond))
+		if err == nil {
+			t.Fatal("UploadLarge() error = nil")
+		}
+		if !u.aborted.Load() {
+			t.Error("failed upload was not aborted")
+		}
+		if uploads, _ := u.ListMultipartUploads(); len(uploads) != 0 {
+			t.Errorf("uploads after abort = %v", uploads)
+		}
+		if u.attempts[2] != 2 {
+			t.Errorf("part 2 attempts = %d, want 2", u.attempts[2])
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		u := &flakyUploader{Uploader: NewLocalUploader(t.TempDir()), attempts: map[int]int{}}
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := UploadLarge(ctx, u, "backup/big.bin", bytes.NewReader(data), int64(len(data)),
+			WithPartSize(64*1024), WithConcurrency(2), WithProgress(func(p Progress) {
+				if p.CompletedParts == 3 {
+					cancel()
+				}
+			}))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("UploadLarge() error = %v, want context.Canceled", err)
+		}
+		if uploads, _ := u.ListMultipartUploads(); len(uploads) != 0 || !u.aborted.Load() {
+			t.Errorf("cancelled upload was not aborted: %v", uploads)
+		}
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		s3, _ := newTestS3Uploader(t)
+		_, err := UploadLarge(context.Background(), s3, "backup/big.bin", bytes.NewReader(data), int64(len(data)), WithPartSize(256*1024))
+		if err != nil {
+			t.Fatalf("UploadLarge() error = %v", err)
+		}
+		testRead(t, s3, "backup/big.bin", string(data))
+	})
+}
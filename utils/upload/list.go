@@ -0,0 +1,83 @@
+package upload
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"iter"
+	"sort"
+
+	"github.com/zhanghaidi/zero-common/utils/pagex"
+)
+
+const (
+	// DefaultListLimit ListObjects 每页默认的数量
+	DefaultListLimit = 1000
+	// maxListLimit 每页数量上限，与 S3、OSS、七牛的限制一致
+	maxListLimit = 1000
+)
+
+// ListOptions 分页列出对象的参数
+type ListOptions struct {
+	Prefix    string // 对象键前缀，按字符串匹配，列出目录时需要以 / 结尾
+	Delimiter string // 通常为 /，设置后 Prefix 下一级的"目录"折叠为一个 IsDir 条目
+	Token     string // 上一页返回的 NextToken，为空时从头开始
+	Limit     int    // 每页的最大数量，为 0 时使用 DefaultListLimit，最大 1000
+}
+
+func (o ListOptions) limit() int {
+	if o.Limit <= 0 {
+		return DefaultListLimit
+	}
+	return min(o.Limit, maxListLimit)
+}
+
+// ObjectPage 一页对象，NextToken 可以直接作为分页接口的响应返回给客户端
+type ObjectPage = pagex.CursorPage[ObjectInfo]
+
+// Objects 逐个返回前缀下的对象，按页请求 ListObjects，遍历中断时不再请求后续页面
+// 出错时返回零值 ObjectInfo 与错误并结束遍历
+func Objects(ctx context.Context, u Uploader, opts ListOptions) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		for {
+			page, err := u.ListObjects(ctx, opts)
+			if err != nil {
+				yield(ObjectInfo{}, err)
+				return
+			}
+			for _, object := range page.List {
+				if !yield(object, nil) {
+					return
+				}
+			}
+			if page.NextToken == "" {
+				return
+			}
+			opts.Token = page.NextToken
+		}
+	}
+}
+
+// encodeToken 将驱动的分页标记编码为客户端不透明的 token
+func encodeToken(marker string) string {
+	if marker == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(marker))
+}
+
+// decodeToken 解码 encodeToken 生成的 token
+func decodeToken(token string) (string, error) {
+	marker, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("upload: invalid page token %q", token)
+	}
+	return string(marker), nil
+}
+
+// sortObjects 按对象键排序，合并存储分别返回的对象与目录
+func sortObjects(objects []ObjectInfo) {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+}
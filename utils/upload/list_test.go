@@ -0,0 +1,92 @@
+package upload
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListObjects(t *testing.T) {
+	s3, _ := newTestS3Uploader(t)
+	uploaders := map[string]Uploader{
+		"local": NewLocalUploader(t.TempDir()),
+		"s3":    s3,
+	}
+	for name, u := range uploaders {
+		t.Run(name, func(t *testing.T) {
+			testListObjects(t, u)
+		})
+	}
+}
+
+// testListObjects 验证分页、目录折叠与迭代器，各驱动返回的顺序一致
+func testListObjects(t *testing.T, u Uploader) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, key := range []string{"list/a.txt", "list/b/1.txt", "list/b/2.txt", "list/c.txt", "list/d/e/3.txt", "other/x.txt"} {
+		if _, err := u.UploadFile(key, strings.NewReader(key)); err != nil {
+			t.Fatalf("UploadFile(%q) error = %v", key, err)
+		}
+	}
+
+	collect := func(opts ListOptions) []string {
+		t.Helper()
+		var keys []string
+		for object, err := range Objects(ctx, u, opts) {
+			if err != nil {
+				t.Fatalf("Objects(%+v) error = %v", opts, err)
+			}
+			if object.IsDir != strings.HasSuffix(object.Key, "/") {
+				t.Errorf("object %+v IsDir mismatch", object)
+			}
+			if !object.IsDir && (object.Size != int64(len(object.Key)) || object.LastModified.IsZero()) {
+				t.Errorf("object %+v has wrong metadata", object)
+			}
+			keys = append(keys, object.Key)
+		}
+		return keys
+	}
+
+	want := []string{"list/a.txt", "list/b/1.txt", "list/b/2.txt", "list/c.txt", "list/d/e/3.txt"}
+	for _, limit := range []int{1, 2, 0} {
+		if got := collect(ListOptions{Prefix: "list/", Limit: limit}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Objects(limit %d) = %v, want %v", limit, got, want)
+		}
+	}
+	want = []string{"list/a.txt", "list/b/", "list/c.txt", "list/d/"}
+	for _, limit := range []int{1, 3} {
+		if got := collect(ListOptions{Prefix: "list/", Delimiter: "/", Limit: limit}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Objects(delimiter, limit %d) = %v, want %v", limit, got, want)
+		}
+	}
+	if got := collect(ListOptions{Prefix: "list/b"}); !reflect.DeepEqual(got, []string{"list/b/1.txt", "list/b/2.txt"}) {
+		t.Errorf("Objects(list/b) = %v", got)
+	}
+	if got := collect(ListOptions{Prefix: "missing/"}); len(got) != 0 {
+		t.Errorf("Objects(missing/) = %v", got)
+	}
+
+	page, err := u.ListObjects(ctx, ListOptions{Prefix: "list/", Delimiter: "/", Limit: 1})
+	if err != nil || len(page.List) != 1 || page.List[0].Key != "list/a.txt" || page.NextToken == "" {
+		t.Fatalf("ListObjects() = %+v, %v", page, err)
+	}
+	page, err = u.ListObjects(ctx, ListOptions{Prefix: "list/", Delimiter: "/", Limit: 1, Token: page.NextToken})
+	if err != nil || len(page.List) != 1 || page.List[0].Key != "list/b/" || !page.List[0].IsDir {
+		t.Fatalf("ListObjects() second page = %+v, %v", page, err)
+	}
+	if _, err = u.ListObjects(ctx, ListOptions{Token: "not a token!"}); err == nil {
+		t.Error("ListObjects() with invalid token error = nil")
+	}
+
+	// 提前结束遍历时不再请求后续页面
+	n := 0
+	for range Objects(ctx, u, ListOptions{Prefix: "list/", Limit: 1}) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Objects() yielded %d objects after break", n)
+	}
+}
@@ -2,8 +2,18 @@
 package upload
 
 import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zhanghaidi/zero-common/config"
+	"github.com/zhanghaidi/zero-common/define"
+	"hash/crc64"
 	"io"
+	"mime"
 	"os"
 	"path"
 	"path/filepath"
@@ -12,126 +22,405 @@ import (
 )
 
 type LocalUploader struct {
-	directory string
+	urlResolver
+	directory  string
+	sessions   SessionStore     // 分片上传会话
+	baseURL    string           // 文件访问地址，预签名地址基于该地址生成
+	signingKey []byte           // 预签名地址使用的密钥
+	now        func() time.Time // 校验预签名地址有效期使用的时钟
+}
+
+// sessionExpiration Redis 中上传会话在最后一次写入后的保留时长
+const sessionExpiration = 7 * 24 * time.Hour
+
+// LocalOption 本地存储的可选配置
+type LocalOption func(*LocalUploader)
+
+// WithBaseURL 设置文件访问地址，Handler 需要挂载在该地址的路径下
+func WithBaseURL(baseURL string) LocalOption {
+	return func(l *LocalUploader) {
+		l.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithSigningKey 设置预签名地址使用的密钥
+func WithSigningKey(key string) LocalOption {
+	return func(l *LocalUploader) {
+		l.signingKey = []byte(key)
+	}
+}
+
+// WithSessionStore 设置分片上传会话的存储，默认保存在存储目录的 chunk-upload/_session 下
+func WithSessionStore(store SessionStore) LocalOption {
+	return func(l *LocalUploader) {
+		l.sessions = store
+	}
+}
+
+// WithCdnURL 设置 URL 使用的 CDN 域名，private 为 true 时 URL 默认生成签名地址
+func WithCdnURL(cdnURL string, private bool) LocalOption {
+	return func(l *LocalUploader) {
+		l.urlResolver = urlResolver{cdnURL: cdnURL, private: private}
+	}
+}
+
+func init() {
+	Register("local", func(cfg config.StorageConf) (Uploader, error) {
+		opts := []LocalOption{
+			WithBaseURL(cfg.Local.BaseUrl),
+			WithSigningKey(cfg.Local.SigningKey),
+			WithCdnURL(cfg.CdnURL, cfg.Private),
+		}
+		if cfg.Local.Session == "redis" {
+			if define.GlobalRedis == nil {
+				return nil, errors.New("upload: redis session store requires define.GlobalRedis")
+			}
+			opts = append(opts, WithSessionStore(NewRedisSessionStore(define.GlobalRedis, sessionExpiration)))
+		}
+		return NewLocalUploader(cfg.Local.Directory, opts...), nil
+	})
 }
 
 // NewLocalUploader 创建一个本地存储上传器实例
-func NewLocalUploader(directory string) *LocalUploader {
-	return &LocalUploader{directory: directory}
+func NewLocalUploader(directory string, opts ...LocalOption) *LocalUploader {
+	l := &LocalUploader{directory: directory, now: time.Now}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.sessions == nil {
+		l.sessions = NewFileSessionStore(filepath.Join(directory, "chunk-upload", "_session"))
+	}
+	return l
 }
 
-// InitiateMultipartUpload 初始化分片上传并返回 uploadID
-func (l *LocalUploader) InitiateMultipartUpload(objectKey string) (string, string, error) {
+// InitiateMultipartUpload 初始化分片上传并返回 uploadID，会话保存在 SessionStore 中以支持断点续传，opts 可以通过 WithObjectKey 指定对象键
+func (l *LocalUploader) InitiateMultipartUpload(objectKey string, opts ...MultipartOption) (string, string, error) {
+	return l.InitiateMultipartUploadCtx(context.Background(), objectKey, opts...)
+}
 
-	uploadId := fmt.Sprintf("%d", time.Now().UnixNano()) // 使用时间戳生成唯一 uploadID
+func (l *LocalUploader) InitiateMultipartUploadCtx(ctx context.Context, objectKey string, opts ...MultipartOption) (string, string, error) {
+	uploadId := rand.Text() // 使用随机值生成唯一 uploadID，避免并发初始化时冲突
 
-	objectKey = fmt.Sprintf("chunk-upload/%s%s", uploadId, strings.ToLower(path.Ext(objectKey)))
+	now := time.Now()
+	session := &UploadSession{
+		UploadID:  uploadId,
+		ObjectKey: fmt.Sprintf("chunk-upload/%s%s", uploadId, strings.ToLower(path.Ext(objectKey))),
+		Parts:     make(map[int]PartInfo),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, opt := range opts {
+		opt(session)
+	}
+	// WithObjectKey 指定的对象键需要在初始化时校验，避免合并时才发现路径无效
+	if _, err := l.objectPath(session.ObjectKey); err != nil {
+		return "", "", err
+	}
+	if err := l.sessions.Create(ctx, session); err != nil {
+		return "", "", fmt.Errorf("failed to create upload session: %w", err)
+	}
 
-	return uploadId, objectKey, nil
+	return uploadId, session.ObjectKey, nil
 }
 
-// UploadPart 上传单个分片
-func (l *LocalUploader) UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64) (string, error) {
-	// 生成分片文件路径
-	partDir := filepath.Join(l.directory, "chunk-upload", "_header")
-	partPath := filepath.Join(partDir, fmt.Sprintf("%s_part%d", uploadID, partNumber))
+// UploadPart 上传单个分片，ETag 为分片内容的 MD5，opts 提供校验值时内容不一致会删除分片
+func (l *LocalUploader) UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	return l.UploadPartCtx(context.Background(), objectKey, uploadID, partNumber, reader, partSize, opts...)
+}
 
-	// 确保分片文件的目录存在
-	if err := os.MkdirAll(filepath.Dir(partPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory for part: %w", err)
+func (l *LocalUploader) UploadPartCtx(ctx context.Context, objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	if _, err := l.session(ctx, objectKey, uploadID); err != nil {
+		return "", err
 	}
 
-	// 创建或覆盖分片文件
-	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0644)
+	// 写入临时文件，校验通过后再替换分片文件，重传失败时保留之前上传的分片
+	file, err := createAtomicFile(l.partPath(uploadID, partNumber), 0644)
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+		return "", fmt.Errorf("failed to create part file: %w", err)
 	}
 	defer file.Close()
 
-	// 将分片内容写入文件
-	if _, err = io.CopyN(file, reader, partSize); err != nil && err != io.EOF {
+	// 将分片内容写入文件，同时计算校验值
+	hr := newHashReader(newCtxReader(ctx, reader))
+	if _, err = io.CopyN(file, hr, partSize); err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to write part: %w", err)
+	}
+	if err = hr.verify(newPartOptions(opts)); err != nil {
+		return "", err
+	}
+	if err = file.Commit(); err != nil {
 		return "", fmt.Errorf("failed to write part: %w", err)
 	}
 
-	// 返回分片 ETag
-	return fmt.Sprintf("etag-%s-part%d", uploadID, partNumber), nil
+	// 返回分片 ETag，与 S3 一致为带引号的 MD5
+	part := PartInfo{
+		PartNumber:   partNumber,
+		ETag:         `"` + hr.MD5() + `"`,
+		Size:         hr.size,
+		Checksum:     hr.MD5(),
+		CRC64:        hr.CRC64(),
+		LastModified: time.Now(),
+	}
+	if err = l.sessions.PutPart(ctx, uploadID, part); err != nil {
+		return "", fmt.Errorf("failed to record part: %w", err)
+	}
+	return part.ETag, nil
+}
+
+// CompleteMultipartUpload 校验分片 ETag 后合并所有分片，返回合并后对象的 MD5 与 CRC64
+func (l *LocalUploader) CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	return l.CompleteMultipartUploadCtx(context.Background(), objectKey, uploadID, parts)
 }
 
-// CompleteMultipartUpload 完成分片上传并合并所有分片
-func (l *LocalUploader) CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (string, error) {
-	finalFilePath := filepath.Join(l.directory, objectKey)
-	// 确保最终文件的目录存在
-	if err := os.MkdirAll(filepath.Dir(finalFilePath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory for final file: %w", err)
+func (l *LocalUploader) CompleteMultipartUploadCtx(ctx context.Context, objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	session, err := l.session(ctx, objectKey, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if session.PartCount > 0 && len(parts) != session.PartCount {
+		return nil, fmt.Errorf("part count mismatch: expected %d, got %d", session.PartCount, len(parts))
+	}
+	var total int64
+	for i, part := range parts {
+		// 与 S3 一致要求分片号严格递增，重复的分片会被合并两次
+		if i > 0 && part.PartNumber <= parts[i-1].PartNumber {
+			return nil, fmt.Errorf("%w: part %d after part %d", ErrInvalidPartOrder, part.PartNumber, parts[i-1].PartNumber)
+		}
+		uploaded, ok := session.Parts[part.PartNumber]
+		if !ok {
+			return nil, fmt.Errorf("part %d has not been uploaded", part.PartNumber)
+		}
+		if strings.Trim(part.ETag, `"`) != strings.Trim(uploaded.ETag, `"`) {
+			return nil, fmt.Errorf("%w: part %d etag %s, uploaded %s", ErrChecksumMismatch, part.PartNumber, part.ETag, uploaded.ETag)
+		}
+		total += uploaded.Size
+	}
+	if session.ObjectSize > 0 && total != session.ObjectSize {
+		return nil, fmt.Errorf("object size mismatch: expected %d, got %d", session.ObjectSize, total)
+	}
+
+	// 合并到临时文件，全部写入并落盘后再替换最终文件，失败或 ctx 取消时最终文件与分片均保持不变
+	finalPath, err := l.objectPath(objectKey)
+	if err != nil {
+		return nil, err
 	}
-	// 创建最终合并文件
-	finalFile, err := os.OpenFile(finalFilePath, os.O_CREATE|os.O_WRONLY, 0644)
+	finalFile, err := createAtomicFile(finalPath, 0644)
 	if err != nil {
-		return "", fmt.Errorf("failed to create final file: %w", err)
+		return nil, fmt.Errorf("failed to create final file: %w", err)
 	}
 	defer finalFile.Close()
 
+	// 合并所有分片，同时计算整个对象的校验值
+	hash := md5.New()
+	crc := crc64.New(crc64Table)
+	size, err := l.mergeParts(ctx, io.MultiWriter(finalFile, hash, crc), uploadID, parts, session)
+	if err != nil {
+		return nil, err
+	}
+	if err = finalFile.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to write final file: %w", err)
+	}
+
+	// 最终文件已经提交，清理分片与会话失败不影响上传结果，残留的分片由 Janitor 回收
+	for _, part := range parts {
+		_ = os.Remove(l.partPath(uploadID, part.PartNumber))
+	}
+	if err = l.sessions.Delete(context.WithoutCancel(ctx), uploadID); err != nil {
+		logx.WithContext(ctx).Errorw("failed to delete upload session", logx.Field("uploadId", uploadID), logx.Field("detail", err))
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	return &CompleteResult{
+		ObjectKey: objectKey,
+		Size:      size,
+		ETag:      `"` + sum + `"`,
+		MD5:       sum,
+		CRC64:     crc.Sum64(),
+	}, nil
+}
+
+// mergeParts 按顺序将分片写入 writer，每个分片开始前及每次读取前检查 ctx
+func (l *LocalUploader) mergeParts(ctx context.Context, writer io.Writer, uploadID string, parts []Part, session *UploadSession) (int64, error) {
 	// 使用缓冲区加速文件写入
 	buffer := make([]byte, 5*1024*1024) // 5MB 缓冲区
-	// 合并所有分片
+	var size int64
 	for _, part := range parts {
-		partFilePath := filepath.Join(l.directory, "chunk-upload", "_header", fmt.Sprintf("%s_part%d", uploadID, part.PartNumber))
-		partFile, err := os.Open(partFilePath)
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		partFile, err := os.Open(l.partPath(uploadID, part.PartNumber))
 		if err != nil {
-			return "", fmt.Errorf("failed to open part file: %w", err)
+			return 0, fmt.Errorf("failed to open part file: %w", err)
 		}
 
-		for {
-			n, err := partFile.Read(buffer)
-			if err != nil && err != io.EOF {
-				partFile.Close()
-				return "", fmt.Errorf("failed to read part file: %w", err)
-			}
-			if n == 0 {
-				break
-			}
-
-			if _, err := finalFile.Write(buffer[:n]); err != nil {
-				partFile.Close()
-				return "", fmt.Errorf("failed to write to final file: %w", err)
-			}
-		}
+		// 分片文件可能在上传后被改动，按记录的校验值复核
+		partHash := md5.New()
+		n, err := io.CopyBuffer(io.MultiWriter(writer, partHash), newCtxReader(ctx, partFile), buffer)
 		partFile.Close()
+		if err != nil {
+			return 0, fmt.Errorf("failed to write to final file: %w", err)
+		}
+		if uploaded := session.Parts[part.PartNumber]; uploaded.Checksum != "" && hex.EncodeToString(partHash.Sum(nil)) != uploaded.Checksum {
+			return 0, fmt.Errorf("%w: part %d content changed", ErrChecksumMismatch, part.PartNumber)
+		}
+		size += n
+	}
+	return size, nil
+}
 
-		// 删除临时分片文件
-		if err = os.Remove(partFilePath); err != nil {
-			return "", fmt.Errorf("failed to delete part file: %w", err)
+// ListParts 列出会话中已上传的分片
+func (l *LocalUploader) ListParts(objectKey, uploadID string) ([]PartInfo, error) {
+	return l.ListPartsCtx(context.Background(), objectKey, uploadID)
+}
+
+func (l *LocalUploader) ListPartsCtx(ctx context.Context, objectKey, uploadID string) ([]PartInfo, error) {
+	session, err := l.session(ctx, objectKey, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return session.SortedParts(), nil
+}
+
+// AbortMultipartUpload 取消分片上传，删除已上传的分片文件和会话
+func (l *LocalUploader) AbortMultipartUpload(objectKey, uploadID string) error {
+	return l.AbortMultipartUploadCtx(context.Background(), objectKey, uploadID)
+}
+
+func (l *LocalUploader) AbortMultipartUploadCtx(ctx context.Context, objectKey, uploadID string) error {
+	session, err := l.session(ctx, objectKey, uploadID)
+	if err != nil {
+		return err
+	}
+
+	for partNumber := range session.Parts {
+		if err = os.Remove(l.partPath(uploadID, partNumber)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete part file: %w", err)
 		}
 	}
-	// 返回合并后的文件路径
-	return objectKey, nil
+	if err = l.sessions.Delete(ctx, uploadID); err != nil {
+		return fmt.Errorf("failed to delete upload session: %w", err)
+	}
+	return nil
 }
 
-// UploadFile 直接上传文件
-func (l *LocalUploader) UploadFile(objectKey string, reader io.Reader) (string, error) {
-	fullPath := filepath.Join(l.directory, objectKey)
-	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+// ListMultipartUploads 列出会话存储中未完成的分片上传
+func (l *LocalUploader) ListMultipartUploads() ([]MultipartUpload, error) {
+	return l.ListMultipartUploadsCtx(context.Background())
+}
+
+func (l *LocalUploader) ListMultipartUploadsCtx(ctx context.Context) ([]MultipartUpload, error) {
+	sessions, err := l.sessions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upload sessions: %w", err)
+	}
+	uploads := make([]MultipartUpload, 0, len(sessions))
+	for _, session := range sessions {
+		uploads = append(uploads, MultipartUpload{
+			ObjectKey: session.ObjectKey,
+			UploadID:  session.UploadID,
+			Initiated: session.CreatedAt,
+		})
 	}
+	return uploads, nil
+}
 
-	file, err := os.Create(fullPath)
+// sweepOrphanParts 删除会话已不存在且早于 before 的分片文件，如 Redis 会话过期后遗留的分片，以及进程崩溃遗留的临时分片文件
+func (l *LocalUploader) sweepOrphanParts(ctx context.Context, before time.Time, dryRun bool) (files int, size int64, err error) {
+	dir := filepath.Join(l.directory, "chunk-upload", "_header")
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, fmt.Errorf("读取分片目录失败: %w", err)
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, reader)
+	for _, entry := range entries {
+		if err = ctx.Err(); err != nil {
+			return files, size, err
+		}
+		uploadID, _, ok := strings.Cut(entry.Name(), "_part")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		// 临时文件以 . 开头，超过 before 仍未重命名说明写入已中断
+		if !strings.HasPrefix(uploadID, ".") {
+			if _, err = l.sessions.Get(ctx, uploadID); !errors.Is(err, ErrSessionNotFound) {
+				continue
+			}
+		}
+		if !dryRun {
+			if err = os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+				return files, size, fmt.Errorf("删除分片文件失败: %w", err)
+			}
+		}
+		files++
+		size += info.Size()
+	}
+	return files, size, nil
+}
+
+// session 获取上传会话并校验对象键
+func (l *LocalUploader) session(ctx context.Context, objectKey, uploadID string) (*UploadSession, error) {
+	// uploadID 会拼接到分片与会话文件名中，只能是单个路径段
+	if uploadID == "" || uploadID == "." || uploadID == ".." || strings.ContainsAny(uploadID, "/\\\x00") {
+		return nil, fmt.Errorf("%w: invalid upload id", ErrSessionNotFound)
+	}
+	if _, err := l.objectPath(objectKey); err != nil {
+		return nil, err
+	}
+	session, err := l.sessions.Get(ctx, uploadID)
 	if err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+		return nil, err
 	}
+	if session.ObjectKey != objectKey {
+		return nil, fmt.Errorf("%w: object key mismatch", ErrSessionNotFound)
+	}
+	return session, nil
+}
+
+// partPath 返回分片文件的路径
+func (l *LocalUploader) partPath(uploadID string, partNumber int) string {
+	return filepath.Join(l.directory, "chunk-upload", "_header", fmt.Sprintf("%s_part%d", uploadID, partNumber))
+}
 
+// UploadFile 直接上传文件，先写入临时文件再重命名，写入失败或 ctx 取消时保留原文件
+func (l *LocalUploader) UploadFile(objectKey string, reader io.Reader) (string, error) {
+	return l.UploadFileCtx(context.Background(), objectKey, reader)
+}
+
+func (l *LocalUploader) UploadFileCtx(ctx context.Context, objectKey string, reader io.Reader) (string, error) {
+	fullPath, err := l.objectPath(objectKey)
+	if err != nil {
+		return "", err
+	}
+	if err = saveFile(ctx, fullPath, reader); err != nil {
+		return "", err
+	}
 	return objectKey, nil
 }
 
 // CopyFolder 复制文件夹
 func (l *LocalUploader) CopyFolder(srcFolder, destFolder string) error {
-	absSrc := filepath.Join(l.directory, srcFolder)
-	absDest := filepath.Join(l.directory, destFolder)
+	return l.CopyFolderCtx(context.Background(), srcFolder, destFolder)
+}
+
+func (l *LocalUploader) CopyFolderCtx(ctx context.Context, srcFolder, destFolder string) error {
+	absSrc, err := l.resolve(srcFolder)
+	if err != nil {
+		return err
+	}
+	absDest, err := l.resolve(destFolder)
+	if err != nil {
+		return err
+	}
+	if within(absSrc, absDest) {
+		return fmt.Errorf("%w: 目标目录 %q 位于源目录 %q 内", ErrInvalidPath, destFolder, srcFolder)
+	}
 
 	// 确保源目录存在
 	if _, err := os.Stat(absSrc); os.IsNotExist(err) {
@@ -142,6 +431,9 @@ func (l *LocalUploader) CopyFolder(srcFolder, destFolder string) error {
 		if err != nil {
 			return fmt.Errorf("访问路径失败 %q: %w", path, err)
 		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 
 		relativePath, err := filepath.Rel(absSrc, path)
 		if err != nil {
@@ -161,11 +453,28 @@ func (l *LocalUploader) CopyFolder(srcFolder, destFolder string) error {
 			return nil
 		}
 
+		// 符号链接必须指向存储目录内，指向目录时不跟随
+		key, err := l.relKey(path)
+		if err != nil {
+			return err
+		}
+		if _, err = l.objectPath(key); err != nil {
+			return err
+		}
+		if targetKey, err := l.relKey(targetPath); err != nil {
+			return err
+		} else if targetPath, err = l.objectPath(targetKey); err != nil {
+			return err
+		}
+
 		// 复制文件
 		info, err := os.Stat(path)
 		if err != nil {
 			return fmt.Errorf("获取文件信息失败: %w", err)
 		}
+		if info.IsDir() {
+			return nil
+		}
 
 		srcFile, err := os.Open(path)
 		if err != nil {
@@ -173,28 +482,92 @@ func (l *LocalUploader) CopyFolder(srcFolder, destFolder string) error {
 		}
 		defer srcFile.Close()
 
-		dstFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+		dstFile, err := createAtomicFile(targetPath, info.Mode())
 		if err != nil {
 			return fmt.Errorf("创建目标文件失败 %q: %w", targetPath, err)
 		}
 		defer dstFile.Close()
 
-		_, err = io.Copy(dstFile, srcFile)
+		_, err = io.Copy(dstFile, newCtxReader(ctx, srcFile))
 		if err != nil {
 			return fmt.Errorf("复制文件失败 %q -> %q: %w", path, targetPath, err)
 		}
 
-		return nil
+		return dstFile.Commit()
 	})
 }
 
+// CopyFile 复制单个文件，同一文件系统内使用硬链接，本地写入都是替换文件，链接后两个对象互不影响
+func (l *LocalUploader) CopyFile(srcKey, destKey string) error {
+	return l.CopyFileCtx(context.Background(), srcKey, destKey)
+}
+
+func (l *LocalUploader) CopyFileCtx(ctx context.Context, srcKey, destKey string) error {
+	srcPath, err := l.objectPath(srcKey)
+	if err != nil {
+		return err
+	}
+	destPath, err := l.objectPath(destKey)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(srcPath)
+	if err != nil || info.IsDir() {
+		if err == nil || os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrObjectNotFound, srcKey)
+		}
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	if srcPath == destPath {
+		return nil
+	}
+	if err = os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	// 链接到临时文件后重命名，目标已存在时原子替换
+	tmpPath := filepath.Join(filepath.Dir(destPath), "."+filepath.Base(destPath)+".tmp-"+rand.Text())
+	if err = os.Link(srcPath, tmpPath); err == nil {
+		if err = os.Rename(tmpPath, destPath); err != nil {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to rename file: %w", err)
+		}
+		syncDir(filepath.Dir(destPath))
+		return nil
+	}
+
+	// 不支持硬链接时复制内容
+	srcFile, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("打开源文件失败 %q: %w", srcKey, err)
+	}
+	defer srcFile.Close()
+
+	dstFile, err := createAtomicFile(destPath, info.Mode())
+	if err != nil {
+		return fmt.Errorf("创建目标文件失败 %q: %w", destKey, err)
+	}
+	defer dstFile.Close()
+
+	if _, err = io.Copy(dstFile, newCtxReader(ctx, srcFile)); err != nil {
+		return fmt.Errorf("复制文件失败 %q -> %q: %w", srcKey, destKey, err)
+	}
+	return dstFile.Commit()
+}
+
 // DeleteFolder 删除目录及其子文件，排除特定文件夹
 func (l *LocalUploader) DeleteFolder(folderPath string, exclude ...string) error {
-	absFolder := filepath.Join(l.directory, folderPath)
+	return l.DeleteFolderCtx(context.Background(), folderPath, exclude...)
+}
 
-	// 确保删除路径在 l.directory 内
-	if !strings.HasPrefix(absFolder, filepath.Clean(l.directory)+string(os.PathSeparator)) {
-		return fmt.Errorf("删除路径不安全: %q 超出 %q", absFolder, l.directory)
+func (l *LocalUploader) DeleteFolderCtx(ctx context.Context, folderPath string, exclude ...string) error {
+	// 不允许删除存储根目录
+	if key, err := cleanKey(folderPath); err != nil || key == "" {
+		return fmt.Errorf("删除路径不安全: %q", folderPath)
+	}
+	absFolder, err := l.resolve(folderPath)
+	if err != nil {
+		return err
 	}
 
 	// 目录不存在时直接跳过
@@ -212,7 +585,7 @@ func (l *LocalUploader) DeleteFolder(folderPath string, exclude ...string) error
 
 	// 先收集路径，确保删除顺序（先文件后目录）
 	var paths []string
-	err := filepath.WalkDir(absFolder, func(path string, d os.DirEntry, err error) error {
+	err = filepath.WalkDir(absFolder, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			if os.IsNotExist(err) {
 				fmt.Printf("Warning: 访问路径失败, 文件已不存在: %s\n", path)
@@ -220,6 +593,9 @@ func (l *LocalUploader) DeleteFolder(folderPath string, exclude ...string) error
 			}
 			return fmt.Errorf("访问路径失败 %q: %w", path, err)
 		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 
 		// 记录路径（文件先，目录后）
 		paths = append(paths, path)
@@ -232,6 +608,9 @@ func (l *LocalUploader) DeleteFolder(folderPath string, exclude ...string) error
 	// **逆序删除，确保先删文件再删目录**
 	for i := len(paths) - 1; i >= 0; i-- {
 		p := paths[i]
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 
 		// 跳过排除项
 		if _, ok := excludeMap[p]; ok {
@@ -248,44 +627,64 @@ func (l *LocalUploader) DeleteFolder(folderPath string, exclude ...string) error
 
 // DeleteFile 删除本地文件
 func (l *LocalUploader) DeleteFile(objectKey string) error {
-	fullPath := filepath.Join(l.directory, objectKey)
+	return l.DeleteFileCtx(context.Background(), objectKey)
+}
 
-	// 确保文件路径在 l.directory 内
-	if !strings.HasPrefix(fullPath, filepath.Clean(l.directory)+string(os.PathSeparator)) {
-		return fmt.Errorf("删除路径不安全: %q 超出 %q", fullPath, l.directory)
+func (l *LocalUploader) DeleteFileCtx(ctx context.Context, objectKey string) error {
+	fullPath, err := l.objectPath(objectKey)
+	if err != nil {
+		return err
 	}
 
-	if err := os.Remove(fullPath); err != nil {
+	if err = os.Remove(fullPath); err != nil {
 		return fmt.Errorf("删除文件失败: %w", err)
 	}
 	return nil
 }
 
-// ListFiles 获取本地目录下的所有文件，可以通过后缀进行过滤
+// ListFiles 获取存储目录下 directory 中的所有文件并返回对象键，可以通过后缀进行过滤
 func (l *LocalUploader) ListFiles(directory string, suffixFilters ...string) ([]string, error) {
-	var files []string
+	return l.ListFilesCtx(context.Background(), directory, suffixFilters...)
+}
 
-	// 遍历目录中的所有文件
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+func (l *LocalUploader) ListFilesCtx(ctx context.Context, directory string, suffixFilters ...string) ([]string, error) {
+	absDir, err := l.resolve(directory)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(absDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var files []string
+	// 遍历目录中的所有文件，不跟随符号链接
+	err = filepath.WalkDir(absDir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("无法访问路径 %q: %w", path, err)
 		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 
-		// 跳过目录，仅处理文件
-		if info.IsDir() {
+		// 跳过目录与写入中的临时文件，仅处理文件
+		if d.IsDir() || isAtomicTemp(d.Name()) {
 			return nil
 		}
+		key, err := l.relKey(path)
+		if err != nil {
+			return err
+		}
 
 		// 如果没有指定后缀过滤器，则添加所有文件
 		if len(suffixFilters) == 0 {
-			files = append(files, path)
+			files = append(files, key)
 			return nil
 		}
 
 		// 按后缀筛选文件
 		for _, suffix := range suffixFilters {
-			if strings.HasSuffix(strings.ToLower(info.Name()), strings.ToLower(suffix)) {
-				files = append(files, path)
+			if strings.HasSuffix(strings.ToLower(d.Name()), strings.ToLower(suffix)) {
+				files = append(files, key)
 				break
 			}
 		}
@@ -298,3 +697,231 @@ func (l *LocalUploader) ListFiles(directory string, suffixFilters ...string) ([]
 
 	return files, nil
 }
+
+// ListObjects 分页列出本地文件，按目录遍历顺序返回，即逐级按名称排序，Delimiter 只支持 /
+func (l *LocalUploader) ListObjects(ctx context.Context, opts ListOptions) (*ObjectPage, error) {
+	if opts.Delimiter != "" && opts.Delimiter != "/" {
+		return nil, fmt.Errorf("upload: local storage only supports delimiter \"/\", got %q", opts.Delimiter)
+	}
+	marker, err := decodeToken(opts.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	// 前缀中最后一个 / 之前的部分为遍历的起始目录
+	baseDir := ""
+	if i := strings.LastIndex(opts.Prefix, "/"); i >= 0 {
+		baseDir = opts.Prefix[:i]
+	}
+	absBase, err := l.resolve(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	page := &ObjectPage{}
+	if _, err = os.Stat(absBase); os.IsNotExist(err) {
+		return page, nil
+	}
+
+	limit := opts.limit()
+	err = filepath.WalkDir(absBase, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("无法访问路径 %q: %w", p, err)
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if p == absBase {
+			return nil
+		}
+		key, err := l.relKey(p)
+		if err != nil {
+			return err
+		}
+
+		var object *ObjectInfo
+		if d.IsDir() {
+			dirKey := key + "/"
+			// 与前缀无关的目录整体跳过
+			if !strings.HasPrefix(dirKey, opts.Prefix) && !strings.HasPrefix(opts.Prefix, dirKey) {
+				return filepath.SkipDir
+			}
+			// 已在之前的页面返回的目录整体跳过，上一页的最后一个对象位于其中时继续遍历
+			if marker != "" && !walkAfter(key, marker) {
+				if strings.HasPrefix(marker, dirKey) && marker != dirKey {
+					return nil
+				}
+				return filepath.SkipDir
+			}
+			if opts.Delimiter == "" || !strings.HasPrefix(dirKey, opts.Prefix) {
+				return nil
+			}
+			object = &ObjectInfo{Key: dirKey, IsDir: true}
+		} else {
+			if isAtomicTemp(d.Name()) || !strings.HasPrefix(key, opts.Prefix) || (marker != "" && !walkAfter(key, marker)) {
+				return nil
+			}
+			info, err := d.Info()
+			// 符号链接必须指向存储目录内的文件
+			if d.Type()&os.ModeSymlink != 0 {
+				var fullPath string
+				if fullPath, err = l.objectPath(key); err == nil {
+					info, err = os.Stat(fullPath)
+				}
+			}
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			object = localObjectInfo(key, info)
+		}
+
+		if len(page.List) == limit {
+			page.NextToken = encodeToken(page.List[limit-1].Key)
+			return filepath.SkipAll
+		}
+		page.List = append(page.List, *object)
+		if object.IsDir {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// walkAfter 判断 key 在目录遍历顺序中是否位于 marker 之后，目录位于其中的文件之前
+func walkAfter(key, marker string) bool {
+	keys := strings.Split(strings.TrimSuffix(key, "/"), "/")
+	markers := strings.Split(strings.TrimSuffix(marker, "/"), "/")
+	for i := 0; i < len(keys) && i < len(markers); i++ {
+		if keys[i] != markers[i] {
+			return keys[i] > markers[i]
+		}
+	}
+	return len(keys) > len(markers)
+}
+
+// readCloser 组合读取范围受限的 Reader 与原文件的 Closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Open 读取本地文件，r 不为 nil 时只读取指定范围
+func (l *LocalUploader) Open(objectKey string, r *Range) (io.ReadCloser, error) {
+	return l.OpenCtx(context.Background(), objectKey, r)
+}
+
+func (l *LocalUploader) OpenCtx(ctx context.Context, objectKey string, r *Range) (io.ReadCloser, error) {
+	fullPath, err := l.objectPath(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+		}
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	if r == nil {
+		return file, nil
+	}
+
+	if _, err = file.Seek(r.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("定位文件失败: %w", err)
+	}
+	if r.Length <= 0 {
+		return file, nil
+	}
+	return readCloser{Reader: io.LimitReader(file, r.Length), Closer: file}, nil
+}
+
+// Download 将本地文件复制到 filePath
+func (l *LocalUploader) Download(objectKey, filePath string, r *Range) error {
+	return l.DownloadCtx(context.Background(), objectKey, filePath, r)
+}
+
+func (l *LocalUploader) DownloadCtx(ctx context.Context, objectKey, filePath string, r *Range) error {
+	reader, err := l.OpenCtx(ctx, objectKey, r)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return saveFile(ctx, filePath, reader)
+}
+
+// Stat 获取本地文件信息，ETag 由修改时间和大小生成
+func (l *LocalUploader) Stat(objectKey string) (*ObjectInfo, error) {
+	return l.StatCtx(context.Background(), objectKey)
+}
+
+func (l *LocalUploader) StatCtx(ctx context.Context, objectKey string) (*ObjectInfo, error) {
+	fullPath, err := l.objectPath(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		if err == nil || os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+		}
+		return nil, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	return localObjectInfo(objectKey, info), nil
+}
+
+// localObjectInfo 根据文件信息生成对象元数据，ETag 由修改时间和大小生成
+func localObjectInfo(objectKey string, info os.FileInfo) *ObjectInfo {
+	contentType := mime.TypeByExtension(path.Ext(objectKey))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &ObjectInfo{
+		Key:          objectKey,
+		Size:         info.Size(),
+		ContentType:  contentType,
+		ETag:         fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+		LastModified: info.ModTime(),
+	}
+}
+
+// Exists 判断本地文件是否存在
+func (l *LocalUploader) Exists(objectKey string) (bool, error) {
+	return l.ExistsCtx(context.Background(), objectKey)
+}
+
+func (l *LocalUploader) ExistsCtx(ctx context.Context, objectKey string) (bool, error) {
+	_, err := l.StatCtx(ctx, objectKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// URL 生成本地文件的访问地址，签名地址由 Handler 校验，不支持图片处理
+func (l *LocalUploader) URL(objectKey string, opts ...URLOption) (string, error) {
+	o := l.options(opts)
+	if o.process != "" {
+		return "", ErrProcessUnsupported
+	}
+
+	var rawURL string
+	if o.signed {
+		u, err := l.PresignGet(objectKey, PresignOptions{Expires: o.expires})
+		if err != nil {
+			return "", err
+		}
+		rawURL = u
+	} else {
+		if l.baseURL == "" && o.domain == "" {
+			return "", errors.New("upload: local base url is not configured")
+		}
+		rawURL = joinURL(l.baseURL, objectKey)
+	}
+	return withDomain(rawURL, o.domain)
+}
@@ -0,0 +1,279 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalUploaderPresign(t *testing.T) {
+	dir := t.TempDir()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	u := NewLocalUploader(dir, WithBaseURL(srv.URL+"/files/"), WithSigningKey("secret"))
+	mux.Handle("/files/", u.Handler())
+
+	do := func(method, rawURL, contentType, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, rawURL, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error = %v", method, rawURL, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	putURL, err := u.PresignPut("avatar/1.png", PresignOptions{ContentType: "image/png", MaxSize: 8})
+	if err != nil {
+		t.Fatalf("PresignPut() error = %v", err)
+	}
+	if resp := do(http.MethodPut, putURL, "image/png", "png"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT status = %d", resp.StatusCode)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "avatar/1.png")); string(data) != "png" {
+		t.Errorf("uploaded file = %q", data)
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"wrong content type", putURL, "image/jpeg", "png", http.StatusUnsupportedMediaType},
+		{"too large", putURL, "image/png", "0123456789", http.StatusRequestEntityTooLarge},
+		{"tampered size", strings.Replace(putURL, "max-size=8", "max-size=80", 1), "image/png", "png", http.StatusForbidden},
+		{"other key", strings.Replace(putURL, "avatar/1.png", "avatar/2.png", 1), "image/png", "png", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := do(http.MethodPut, tt.url, tt.contentType, tt.body); resp.StatusCode != tt.status {
+				t.Errorf("PUT status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+	if _, err = os.Stat(filepath.Join(dir, "avatar/2.png")); !os.IsNotExist(err) {
+		t.Errorf("rejected upload created a file: %v", err)
+	}
+
+	getURL, err := u.PresignGet("avatar/1.png", PresignOptions{Expires: time.Minute})
+	if err != nil {
+		t.Fatalf("PresignGet() error = %v", err)
+	}
+	resp := do(http.MethodGet, getURL, "", "")
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(body) != "png" {
+		t.Errorf("GET = %d %q", resp.StatusCode, body)
+	}
+	if resp := do(http.MethodPut, getURL, "", "x"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("PUT with GET signature status = %d", resp.StatusCode)
+	}
+
+	u.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if resp := do(http.MethodGet, getURL, "", ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("GET expired url status = %d", resp.StatusCode)
+	}
+
+	if _, err = NewLocalUploader(dir).PresignGet("avatar/1.png", PresignOptions{}); err == nil {
+		t.Error("PresignGet() without signing key should fail")
+	}
+}
+
+func TestLocalUploaderRead(t *testing.T) {
+	u := NewLocalUploader(t.TempDir())
+	if _, err := u.UploadFile("course/a/1.mp4", strings.NewReader("video-1")); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	testRead(t, u, "course/a/1.mp4", "video-1")
+
+	if info, _ := u.Stat("course/a/1.mp4"); info.ContentType != "video/mp4" {
+		t.Errorf("Stat() ContentType = %q", info.ContentType)
+	}
+	if _, err := u.Open("../secret", nil); err == nil {
+		t.Error("Open() outside directory should fail")
+	}
+}
+
+func TestLocalUploaderURL(t *testing.T) {
+	u := NewLocalUploader(t.TempDir(), WithBaseURL("http://127.0.0.1:8888/files"), WithSigningKey("secret"))
+
+	if got, err := u.URL("avatar/a b.png"); err != nil || got != "http://127.0.0.1:8888/files/avatar/a%20b.png" {
+		t.Errorf("URL() = %q, %v", got, err)
+	}
+	if got, _ := u.URL("avatar/1.png", WithDomain("cdn.example.com")); got != "https://cdn.example.com/files/avatar/1.png" {
+		t.Errorf("URL() with domain = %q", got)
+	}
+	if got, _ := u.URL("avatar/1.png", WithExpires(time.Minute)); !strings.Contains(got, "signature=") {
+		t.Errorf("URL() signed = %q", got)
+	}
+	if _, err := u.URL("avatar/1.png", WithProcess("image/resize,w_200")); err != ErrProcessUnsupported {
+		t.Errorf("URL() with process error = %v", err)
+	}
+}
+
+// cancelReader 读取一次后取消 ctx，模拟客户端在传输中断开
+type cancelReader struct {
+	reader io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p[:min(len(p), 4)])
+	r.cancel()
+	return n, err
+}
+
+func TestLocalUploaderCancel(t *testing.T) {
+	dir := t.TempDir()
+	u := NewLocalUploader(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := u.UploadFileCtx(ctx, "cancel/file.txt", &cancelReader{reader: strings.NewReader("0123456789"), cancel: cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("UploadFileCtx() error = %v, want context.Canceled", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "cancel/file.txt")); !os.IsNotExist(err) {
+		t.Errorf("cancelled upload left a file: %v", err)
+	}
+
+	uploadID, objectKey, err := u.InitiateMultipartUpload("video.mp4")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	var parts []Part
+	for i, content := range []string{"part-1", "part-2"} {
+		etag, err := u.UploadPart(objectKey, uploadID, i+1, strings.NewReader(content), int64(len(content)))
+		if err != nil {
+			t.Fatalf("UploadPart(%d) error = %v", i+1, err)
+		}
+		parts = append(parts, Part{PartNumber: i + 1, ETag: etag})
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err = u.CompleteMultipartUploadCtx(ctx, objectKey, uploadID, parts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CompleteMultipartUploadCtx() error = %v, want context.Canceled", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, objectKey)); !os.IsNotExist(err) {
+		t.Errorf("cancelled merge left a file: %v", err)
+	}
+	// 取消后分片与会话保留，可以重新合并
+	res, err := u.CompleteMultipartUpload(objectKey, uploadID, parts)
+	if err != nil {
+		t.Fatalf("CompleteMultipartUpload() after cancel error = %v", err)
+	}
+	if res.Size != 12 {
+		t.Errorf("Size = %d, want 12", res.Size)
+	}
+
+	if err = u.DeleteFolderCtx(ctx, "chunk-upload"); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeleteFolderCtx() error = %v, want context.Canceled", err)
+	}
+	if err = u.CopyFolderCtx(ctx, "chunk-upload", "copy"); !errors.Is(err, context.Canceled) {
+		t.Errorf("CopyFolderCtx() error = %v, want context.Canceled", err)
+	}
+	if exists, _ := u.Exists(objectKey); !exists {
+		t.Errorf("cancelled DeleteFolderCtx removed %s", objectKey)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestLocalUploaderAtomicWrite(t *testing.T) {
+	dir := t.TempDir()
+	u := NewLocalUploader(dir)
+
+	if _, err := u.UploadFile("atomic/a.txt", strings.NewReader("old content")); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	broken := io.MultiReader(strings.NewReader("new"), errReader{errors.New("connection reset")})
+	if _, err := u.UploadFile("atomic/a.txt", broken); err == nil {
+		t.Fatal("UploadFile() with broken reader error = nil")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "atomic/a.txt")); string(data) != "old content" {
+		t.Errorf("failed upload changed file to %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "atomic")); len(entries) != 1 {
+		t.Errorf("failed upload left temp files: %v", entries)
+	}
+
+	uploadID, objectKey, err := u.InitiateMultipartUpload("a.txt")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	// 目标路径已存在更长的文件，合并后不能残留尾部内容
+	if _, err = u.UploadFile(objectKey, strings.NewReader(strings.Repeat("x", 64))); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	etag, err := u.UploadPart(objectKey, uploadID, 1, strings.NewReader("short"), 5)
+	if err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+	// 重传失败的分片不会覆盖已上传的分片
+	if _, err = u.UploadPart(objectKey, uploadID, 1, strings.NewReader("bad"), 3, WithContentMD5("AAAAAAAAAAAAAAAAAAAAAA==")); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("UploadPart() with bad md5 error = %v", err)
+	}
+	if _, err = u.CompleteMultipartUpload(objectKey, uploadID, []Part{{PartNumber: 1, ETag: etag}}); err != nil {
+		t.Fatalf("CompleteMultipartUpload() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, objectKey)); string(data) != "short" {
+		t.Errorf("merged file = %q, want %q", data, "short")
+	}
+}
+
+func TestLocalUploaderPartOrder(t *testing.T) {
+	u := NewLocalUploader(t.TempDir())
+	uploadID, objectKey, err := u.InitiateMultipartUpload("order.txt")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	var parts []Part
+	for i, chunk := range []string{"a", "b"} {
+		etag, err := u.UploadPart(objectKey, uploadID, i+1, strings.NewReader(chunk), 1)
+		if err != nil {
+			t.Fatalf("UploadPart(%d) error = %v", i+1, err)
+		}
+		parts = append(parts, Part{PartNumber: i + 1, ETag: etag})
+	}
+
+	for _, invalid := range [][]Part{
+		{parts[0], parts[0]},
+		{parts[1], parts[0]},
+	} {
+		if _, err = u.CompleteMultipartUpload(objectKey, uploadID, invalid); !errors.Is(err, ErrInvalidPartOrder) {
+			t.Errorf("CompleteMultipartUpload(%+v) error = %v, want ErrInvalidPartOrder", invalid, err)
+		}
+	}
+	// 被拒绝的请求不影响会话，修正后可以继续完成上传
+	if exists, _ := u.Exists(objectKey); exists {
+		t.Error("rejected complete created the object")
+	}
+	if res, err := u.CompleteMultipartUpload(objectKey, uploadID, parts); err != nil || res.Size != 2 {
+		t.Fatalf("CompleteMultipartUpload() = %+v, %v", res, err)
+	}
+}
+
+func TestLocalUploaderInvalidObjectKey(t *testing.T) {
+	u := NewLocalUploader(t.TempDir())
+	if _, _, err := u.InitiateMultipartUpload("a.bin", WithObjectKey("../escape.bin")); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("InitiateMultipartUpload() error = %v, want ErrInvalidPath", err)
+	}
+	if uploads, _ := u.ListMultipartUploads(); len(uploads) != 0 {
+		t.Errorf("rejected upload created a session: %v", uploads)
+	}
+}
@@ -0,0 +1,103 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidPath 对象键或目录不合法，或解析后位于存储目录之外
+var ErrInvalidPath = errors.New("upload: invalid path")
+
+// cleanKey 校验以 / 分隔的相对路径并去掉结尾的 /，拒绝空段、.、..、反斜杠、NUL 与绝对路径，存储根目录返回空字符串
+func cleanKey(name string) (string, error) {
+	key := strings.TrimSuffix(name, "/")
+	if key == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(key, "\\\x00") || path.Clean("/"+key) != "/"+key || filepath.VolumeName(key) != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPath, name)
+	}
+	return key, nil
+}
+
+// resolve 将对象键或目录解析为存储目录下的绝对路径，所有访问文件系统的方法都必须经过这里
+// 已存在的部分会解析符号链接，指向存储目录之外时返回 ErrInvalidPath
+func (l *LocalUploader) resolve(name string) (string, error) {
+	key, err := cleanKey(name)
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.Abs(l.directory)
+	if err != nil {
+		return "", fmt.Errorf("解析存储目录失败: %w", err)
+	}
+	fullPath := filepath.Join(root, filepath.FromSlash(key))
+
+	realRoot, err := evalExisting(root)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := evalExisting(fullPath)
+	if err != nil {
+		return "", err
+	}
+	if !within(realRoot, realPath) {
+		return "", fmt.Errorf("%w: %q 超出存储目录", ErrInvalidPath, name)
+	}
+	return fullPath, nil
+}
+
+// objectPath 返回对象在本地的路径，对象键不能为空或指向存储根目录
+func (l *LocalUploader) objectPath(objectKey string) (string, error) {
+	if key, err := cleanKey(objectKey); err != nil || key == "" || strings.HasSuffix(objectKey, "/") {
+		return "", fmt.Errorf("%w: object key %q", ErrInvalidPath, objectKey)
+	}
+	return l.resolve(objectKey)
+}
+
+// relKey 将存储目录下的路径转换为以 / 分隔的对象键
+func (l *LocalUploader) relKey(fullPath string) (string, error) {
+	root, err := filepath.Abs(l.directory)
+	if err != nil {
+		return "", fmt.Errorf("解析存储目录失败: %w", err)
+	}
+	rel, err := filepath.Rel(root, fullPath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w: %q 超出存储目录", ErrInvalidPath, fullPath)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// evalExisting 解析路径中已存在部分的符号链接，不存在的部分原样拼接
+func evalExisting(p string) (string, error) {
+	var rest []string
+	for {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: %v", ErrInvalidPath, err)
+		}
+		// 指向不存在目标的符号链接，无法确定写入位置
+		if _, lerr := os.Lstat(p); lerr == nil {
+			return "", fmt.Errorf("%w: dangling symlink %q", ErrInvalidPath, p)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(append([]string{p}, rest...)...), nil
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+		p = parent
+	}
+}
+
+// within 判断 target 是否为 root 或位于 root 之下
+func within(root, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
@@ -0,0 +1,130 @@
+package upload
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newSandbox 创建存储目录 root 与同级的 outside 目录，root/escape 为指向 outside 的符号链接
+func newSandbox(tb testing.TB, dir string) (root, outside string) {
+	tb.Helper()
+	root = filepath.Join(dir, "root")
+	outside = filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "inner"), outside} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		tb.Skipf("symlink not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "inner"), filepath.Join(root, "alias")); err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing"), filepath.Join(root, "dangling")); err != nil {
+		tb.Fatal(err)
+	}
+	return root, outside
+}
+
+func TestLocalUploaderResolve(t *testing.T) {
+	root, _ := newSandbox(t, t.TempDir())
+	u := NewLocalUploader(root)
+
+	tests := []struct {
+		key string
+		ok  bool
+	}{
+		{"a/b.txt", true},
+		{"inner/b.txt", true},
+		{"alias/b.txt", true},
+		{"..", false},
+		{"../outside/x", false},
+		{"a/../../x", false},
+		{"a/../b", false},
+		{"./a", false},
+		{"a//b", false},
+		{"/etc/passwd", false},
+		{`a\..\..\x`, false},
+		{"a\x00b", false},
+		{"escape/x", false},
+		{"escape", false},
+		{"dangling", false},
+		{"", false},
+		{"a/", false},
+	}
+	for _, tt := range tests {
+		_, err := u.objectPath(tt.key)
+		if (err == nil) != tt.ok {
+			t.Errorf("objectPath(%q) error = %v, want ok %v", tt.key, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("objectPath(%q) error = %v, want ErrInvalidPath", tt.key, err)
+		}
+	}
+
+	if _, err := u.UploadFile("escape/x.txt", strings.NewReader("x")); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("UploadFile() through symlink error = %v", err)
+	}
+	if _, err := u.ListFiles("/"); err != nil {
+		t.Errorf("ListFiles(root) error = %v", err)
+	}
+	if _, err := u.ListFiles("../"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("ListFiles(../) error = %v", err)
+	}
+	if err := u.CopyFolder("escape", "copy"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("CopyFolder() from symlink error = %v", err)
+	}
+	if err := u.DeleteFolder(""); err == nil {
+		t.Error("DeleteFolder(root) error = nil")
+	}
+	if _, err := u.ListParts("chunk-upload/x.mp4", "../../escape/x"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("ListParts() with traversal upload id error = %v", err)
+	}
+}
+
+// FuzzLocalUploaderPath 任意对象键都不能使文件写入、复制或删除到存储目录之外
+func FuzzLocalUploaderPath(f *testing.F) {
+	for _, seed := range []string{
+		"a.txt", "a/b/c.txt", "../x", "../../outside/x", "a/../../x", "/etc/passwd",
+		`..\x`, "escape/x", "escape/../x", "alias/x", "dangling", ".", "./", "a/./b", "a//b", "\x00",
+	} {
+		f.Add(seed)
+	}
+
+	dir := f.TempDir()
+	root, outside := newSandbox(f, dir)
+	u := NewLocalUploader(root)
+
+	f.Fuzz(func(t *testing.T, key string) {
+		if fullPath, err := u.objectPath(key); err == nil {
+			real, err := evalExisting(fullPath)
+			if err != nil {
+				t.Fatalf("evalExisting(%q) error = %v", fullPath, err)
+			}
+			realRoot, _ := filepath.EvalSymlinks(root)
+			if !within(realRoot, real) {
+				t.Fatalf("objectPath(%q) = %q escapes %q", key, real, realRoot)
+			}
+		}
+
+		_, _ = u.UploadFile(key, strings.NewReader("x"))
+		_ = u.CopyFolder("inner", key)
+		_ = u.DeleteFile(key)
+		_ = u.DeleteFolder(key)
+
+		if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+			t.Fatalf("key %q wrote into outside directory: %v", key, entries)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+			t.Fatalf("key %q changed the parent directory: %v", key, entries)
+		}
+		if _, err := os.Lstat(filepath.Join(root, "escape")); err != nil {
+			// DeleteFolder 可以删除符号链接本身，重建以便后续输入继续验证
+			_ = os.Symlink(outside, filepath.Join(root, "escape"))
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package upload
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// errLocalSigningKey 未配置签名密钥
+var errLocalSigningKey = errors.New("upload: local presign requires a signing key")
+
+// PresignPut 生成直接上传的签名地址，由 Handler 校验签名、类型与大小后写入
+func (l *LocalUploader) PresignPut(objectKey string, opts PresignOptions) (string, error) {
+	return l.presign(http.MethodPut, objectKey, opts)
+}
+
+// PresignGet 生成直接下载的签名地址
+func (l *LocalUploader) PresignGet(objectKey string, opts PresignOptions) (string, error) {
+	return l.presign(http.MethodGet, objectKey, PresignOptions{Expires: opts.Expires})
+}
+
+func (l *LocalUploader) presign(method, objectKey string, opts PresignOptions) (string, error) {
+	if len(l.signingKey) == 0 {
+		return "", errLocalSigningKey
+	}
+	if !validObjectKey(objectKey) {
+		return "", fmt.Errorf("upload: invalid object key %q", objectKey)
+	}
+
+	query := url.Values{"expires": {strconv.FormatInt(l.now().Add(opts.expires()).Unix(), 10)}}
+	if opts.ContentType != "" {
+		query.Set("content-type", opts.ContentType)
+	}
+	if opts.MaxSize > 0 {
+		query.Set("max-size", strconv.FormatInt(opts.MaxSize, 10))
+	}
+	query.Set("signature", l.signature(method, objectKey, query))
+
+	return l.baseURL + "/" + uriEncode(objectKey, false) + "?" + query.Encode(), nil
+}
+
+// signature 对请求方法、对象键以及地址中的限制条件签名
+func (l *LocalUploader) signature(method, objectKey string, query url.Values) string {
+	h := hmac.New(sha256.New, l.signingKey)
+	h.Write([]byte(strings.Join([]string{
+		method,
+		objectKey,
+		query.Get("expires"),
+		query.Get("content-type"),
+		query.Get("max-size"),
+	}, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Handler 返回处理预签名地址的 http.Handler，需要挂载在 BaseURL 对应的路径下
+func (l *LocalUploader) Handler() http.Handler {
+	basePath := ""
+	if u, err := url.Parse(l.baseURL); err == nil {
+		basePath = strings.TrimSuffix(u.Path, "/")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		objectKey, ok := strings.CutPrefix(r.URL.Path, basePath+"/")
+		if !ok || !validObjectKey(objectKey) {
+			http.NotFound(w, r)
+			return
+		}
+
+		method := r.Method
+		if method == http.MethodHead {
+			method = http.MethodGet
+		}
+		if method != http.MethodGet && method != http.MethodPut {
+			w.Header().Set("Allow", "GET, HEAD, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := l.verify(method, objectKey, r.URL.Query()); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if method == http.MethodPut {
+			l.servePut(w, r, objectKey)
+			return
+		}
+		l.serveGet(w, r, objectKey)
+	})
+}
+
+// verify 校验签名与有效期
+func (l *LocalUploader) verify(method, objectKey string, query url.Values) error {
+	if len(l.signingKey) == 0 {
+		return errLocalSigningKey
+	}
+	want := l.signature(method, objectKey, query)
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(want)) {
+		return errors.New("signature mismatch")
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || l.now().Unix() > expires {
+		return errors.New("url expired")
+	}
+	return nil
+}
+
+func (l *LocalUploader) servePut(w http.ResponseWriter, r *http.Request, objectKey string) {
+	query := r.URL.Query()
+	if contentType := query.Get("content-type"); contentType != "" && r.Header.Get("Content-Type") != contentType {
+		http.Error(w, "content type mismatch", http.StatusUnsupportedMediaType)
+		return
+	}
+	if maxSize, _ := strconv.ParseInt(query.Get("max-size"), 10, 64); maxSize > 0 {
+		if r.ContentLength > maxSize {
+			http.Error(w, "request entity too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	// 上传中断时 UploadFile 不会留下不完整的文件，也不会覆盖原文件
+	if _, err := l.UploadFileCtx(r.Context(), objectKey, r.Body); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "request entity too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "upload failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (l *LocalUploader) serveGet(w http.ResponseWriter, r *http.Request, objectKey string) {
+	fullPath, err := l.objectPath(objectKey)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.Open(fullPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// validObjectKey 对象键必须是不含 . 与 .. 的相对路径
+func validObjectKey(objectKey string) bool {
+	return objectKey != "" && path.Clean("/"+objectKey) == "/"+objectKey
+}
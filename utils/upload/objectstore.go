@@ -0,0 +1,363 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// objectPartSize UploadFile 在数据长度未知时使用的分片大小，S3 要求除最后一片外不小于 5MB
+const objectPartSize = 16 * 1024 * 1024
+
+// objectStore 基于 objectClient 实现 Uploader，供 S3 与 COS 等 S3 XML 协议的驱动复用
+type objectStore struct {
+	urlResolver
+	client    *objectClient
+	name      string // 错误信息中使用的存储名称
+	publicURL string // 存储桶公开访问地址
+}
+
+// InitiateMultipartUpload 初始化分片上传，会话由服务端保存，opts 中只有 WithObjectKey 生效
+func (s *objectStore) InitiateMultipartUpload(objectKey string, opts ...MultipartOption) (string, string, error) {
+	return s.InitiateMultipartUploadCtx(context.Background(), objectKey, opts...)
+}
+
+func (s *objectStore) InitiateMultipartUploadCtx(ctx context.Context, objectKey string, opts ...MultipartOption) (string, string, error) {
+	objectKey = multipartKey(objectKey, opts)
+
+	uploadID, err := s.client.initiateMultipartUpload(ctx, objectKey, mime.TypeByExtension(path.Ext(objectKey)))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to initiate multipart upload: %w", err)
+	}
+
+	return uploadID, objectKey, nil
+}
+
+// UploadPart 上传分片，MD5 由服务端通过 Content-MD5 校验，CRC64 在上传后按实际发送的内容校验，
+// 上传后校验失败时分片已写入服务端，会取消整个上传
+func (s *objectStore) UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	return s.UploadPartCtx(context.Background(), objectKey, uploadID, partNumber, reader, partSize, opts...)
+}
+
+func (s *objectStore) UploadPartCtx(ctx context.Context, objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	o := newPartOptions(opts)
+	hr := newHashReader(reader)
+	etag, err := s.client.uploadPart(ctx, objectKey, uploadID, partNumber, hr, partSize, o.contentMD5)
+	if err != nil {
+		var objErr *objectError
+		if errors.As(err, &objErr) && (objErr.Code == "BadDigest" || objErr.Code == "InvalidDigest") {
+			return "", fmt.Errorf("%w: %v", ErrChecksumMismatch, err)
+		}
+		return "", fmt.Errorf("failed to upload part: %w", err)
+	}
+	if err = hr.verify(o); err != nil {
+		return "", abortMismatch(ctx, err, func(ctx context.Context) error {
+			return s.AbortMultipartUploadCtx(ctx, objectKey, uploadID)
+		})
+	}
+	return etag, nil
+}
+
+// CompleteMultipartUpload 完成分片上传，分片 ETag 由服务端校验，大小与 CRC64 通过 HEAD 获取
+func (s *objectStore) CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	return s.CompleteMultipartUploadCtx(context.Background(), objectKey, uploadID, parts)
+}
+
+func (s *objectStore) CompleteMultipartUploadCtx(ctx context.Context, objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	etag, err := s.client.completeMultipartUpload(ctx, objectKey, uploadID, parts)
+	if err != nil {
+		var objErr *objectError
+		if errors.As(err, &objErr) && objErr.Code == "InvalidPart" {
+			return nil, fmt.Errorf("%w: %v", ErrChecksumMismatch, err)
+		}
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	info, crc, err := s.client.headObjectCRC64(ctx, objectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s object: %w", s.name, err)
+	}
+	if etag == "" {
+		etag = info.ETag
+	}
+	return &CompleteResult{ObjectKey: objectKey, Size: info.Size, ETag: etag, CRC64: crc}, nil
+}
+
+// ListParts 列出已上传的分片
+func (s *objectStore) ListParts(objectKey, uploadID string) ([]PartInfo, error) {
+	return s.ListPartsCtx(context.Background(), objectKey, uploadID)
+}
+
+func (s *objectStore) ListPartsCtx(ctx context.Context, objectKey, uploadID string) ([]PartInfo, error) {
+	parts, err := s.client.listParts(ctx, objectKey, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+	return parts, nil
+}
+
+// AbortMultipartUpload 取消分片上传
+func (s *objectStore) AbortMultipartUpload(objectKey, uploadID string) error {
+	return s.AbortMultipartUploadCtx(context.Background(), objectKey, uploadID)
+}
+
+func (s *objectStore) AbortMultipartUploadCtx(ctx context.Context, objectKey, uploadID string) error {
+	if err := s.client.abortMultipartUpload(ctx, objectKey, uploadID); err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+// ListMultipartUploads 列出存储桶中未完成的分片上传
+func (s *objectStore) ListMultipartUploads() ([]MultipartUpload, error) {
+	return s.ListMultipartUploadsCtx(context.Background())
+}
+
+func (s *objectStore) ListMultipartUploadsCtx(ctx context.Context) ([]MultipartUpload, error) {
+	uploads, err := s.client.listMultipartUploads(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+	}
+	return uploads, nil
+}
+
+// UploadFile 直接上传文件，超过一个分片大小时自动转为分片上传
+func (s *objectStore) UploadFile(objectKey string, reader io.Reader) (string, error) {
+	return s.UploadFileCtx(context.Background(), objectKey, reader)
+}
+
+func (s *objectStore) UploadFileCtx(ctx context.Context, objectKey string, reader io.Reader) (string, error) {
+	contentType := mime.TypeByExtension(path.Ext(objectKey))
+	if err := s.client.uploadStream(ctx, objectKey, reader, objectPartSize, contentType); err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	return objectKey, nil
+}
+
+// CopyFolder 复制文件夹及其子文件到新路径
+func (s *objectStore) CopyFolder(srcFolder, destFolder string) error {
+	return s.CopyFolderCtx(context.Background(), srcFolder, destFolder)
+}
+
+func (s *objectStore) CopyFolderCtx(ctx context.Context, srcFolder, destFolder string) error {
+	return s.client.walkObjects(ctx, srcFolder, func(objects []objectSummary) error {
+		for _, object := range objects {
+			destKey := strings.Replace(object.Key, srcFolder, destFolder, 1)
+			if err := s.client.copyObject(ctx, object.Key, destKey); err != nil {
+				return fmt.Errorf("复制%s对象失败: %v", s.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// CopyFile 在存储桶内复制单个对象，超过单次复制上限的对象使用分片复制
+func (s *objectStore) CopyFile(srcKey, destKey string) error {
+	return s.CopyFileCtx(context.Background(), srcKey, destKey)
+}
+
+func (s *objectStore) CopyFileCtx(ctx context.Context, srcKey, destKey string) error {
+	if err := s.client.copyFile(ctx, srcKey, destKey); err != nil {
+		return s.wrapError(srcKey, "复制", err)
+	}
+	return nil
+}
+
+// DeleteFolder 删除文件夹及其子文件，排除指定的子文件夹
+func (s *objectStore) DeleteFolder(folderPath string, exclude ...string) error {
+	return s.DeleteFolderCtx(context.Background(), folderPath, exclude...)
+}
+
+func (s *objectStore) DeleteFolderCtx(ctx context.Context, folderPath string, exclude ...string) error {
+
+	// 先收集再删除，避免删除过程中影响分页
+	var keys []string
+	err := s.client.walkObjects(ctx, folderPath, func(objects []objectSummary) error {
+		for _, object := range objects {
+			if !isExcluded(object.Key, folderPath, exclude) {
+				keys = append(keys, object.Key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("列出%s对象失败: %v", s.name, err)
+	}
+
+	if err = s.client.deleteObjects(ctx, keys); err != nil {
+		return fmt.Errorf("删除%s对象失败: %v", s.name, err)
+	}
+	return nil
+}
+
+// DeleteFile 删除文件
+func (s *objectStore) DeleteFile(objectKey string) error {
+	return s.DeleteFileCtx(context.Background(), objectKey)
+}
+
+func (s *objectStore) DeleteFileCtx(ctx context.Context, objectKey string) error {
+	if err := s.client.deleteObject(ctx, objectKey); err != nil {
+		return fmt.Errorf("删除%s文件失败: %v", s.name, err)
+	}
+	return nil
+}
+
+// ListFiles 获取存储桶目录下的所有文件列表，可以通过后缀进行过滤
+func (s *objectStore) ListFiles(directory string, suffixFilters ...string) ([]string, error) {
+	return s.ListFilesCtx(context.Background(), directory, suffixFilters...)
+}
+
+func (s *objectStore) ListFilesCtx(ctx context.Context, directory string, suffixFilters ...string) ([]string, error) {
+	var files []string
+	err := s.client.walkObjects(ctx, directory, func(objects []objectSummary) error {
+		for _, object := range objects {
+			if hasSuffix(object.Key, suffixFilters) {
+				files = append(files, object.Key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列出%s对象失败: %v", s.name, err)
+	}
+	return files, nil
+}
+
+// ListObjects 分页列出对象，按对象键排序
+func (s *objectStore) ListObjects(ctx context.Context, opts ListOptions) (*ObjectPage, error) {
+	marker, err := decodeToken(opts.Token)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.listObjects(ctx, opts.Prefix, marker, opts.Delimiter, opts.limit())
+	if err != nil {
+		return nil, fmt.Errorf("列出%s对象失败: %v", s.name, err)
+	}
+
+	page := &ObjectPage{List: make([]ObjectInfo, 0, len(res.Contents)+len(res.CommonPrefixes))}
+	for _, object := range res.Contents {
+		page.List = append(page.List, ObjectInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			ETag:         object.ETag,
+			LastModified: object.LastModified,
+		})
+	}
+	for _, prefix := range res.CommonPrefixes {
+		page.List = append(page.List, ObjectInfo{Key: prefix.Prefix, IsDir: true})
+	}
+	sortObjects(page.List)
+	if res.IsTruncated {
+		page.NextToken = encodeToken(res.NextMarker)
+	}
+	return page, nil
+}
+
+// PresignPut 生成直接上传的预签名地址，S3 协议的 PUT 请求无法限制上传大小
+func (s *objectStore) PresignPut(objectKey string, opts PresignOptions) (string, error) {
+	if opts.MaxSize > 0 {
+		return "", ErrPresignMaxSize
+	}
+	header := make(http.Header)
+	if opts.ContentType != "" {
+		header.Set("Content-Type", opts.ContentType)
+	}
+
+	u, err := s.client.presignURL(http.MethodPut, objectKey, header, opts.expires())
+	if err != nil {
+		return "", fmt.Errorf("failed to presign url: %w", err)
+	}
+	return u, nil
+}
+
+// PresignGet 生成直接下载的预签名地址
+func (s *objectStore) PresignGet(objectKey string, opts PresignOptions) (string, error) {
+	u, err := s.client.presignURL(http.MethodGet, objectKey, nil, opts.expires())
+	if err != nil {
+		return "", fmt.Errorf("failed to presign url: %w", err)
+	}
+	return u, nil
+}
+
+// Open 读取对象内容，r 不为 nil 时只读取指定范围
+func (s *objectStore) Open(objectKey string, r *Range) (io.ReadCloser, error) {
+	return s.OpenCtx(context.Background(), objectKey, r)
+}
+
+func (s *objectStore) OpenCtx(ctx context.Context, objectKey string, r *Range) (io.ReadCloser, error) {
+	rangeHeader := ""
+	if r != nil {
+		rangeHeader = r.header()
+	}
+	body, err := s.client.getObject(ctx, objectKey, rangeHeader)
+	if err != nil {
+		return nil, s.wrapError(objectKey, "读取", err)
+	}
+	return body, nil
+}
+
+// Download 将对象内容保存到本地文件
+func (s *objectStore) Download(objectKey, filePath string, r *Range) error {
+	return s.DownloadCtx(context.Background(), objectKey, filePath, r)
+}
+
+func (s *objectStore) DownloadCtx(ctx context.Context, objectKey, filePath string, r *Range) error {
+	body, err := s.OpenCtx(ctx, objectKey, r)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return saveFile(ctx, filePath, body)
+}
+
+// Stat 获取对象元数据
+func (s *objectStore) Stat(objectKey string) (*ObjectInfo, error) {
+	return s.StatCtx(context.Background(), objectKey)
+}
+
+func (s *objectStore) StatCtx(ctx context.Context, objectKey string) (*ObjectInfo, error) {
+	info, err := s.client.headObject(ctx, objectKey)
+	if err != nil {
+		return nil, s.wrapError(objectKey, "获取", err)
+	}
+	return info, nil
+}
+
+// Exists 判断对象是否存在
+func (s *objectStore) Exists(objectKey string) (bool, error) {
+	return s.ExistsCtx(context.Background(), objectKey)
+}
+
+func (s *objectStore) ExistsCtx(ctx context.Context, objectKey string) (bool, error) {
+	_, err := s.StatCtx(ctx, objectKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// wrapError 对象不存在时转换为 ErrObjectNotFound
+func (s *objectStore) wrapError(objectKey, action string, err error) error {
+	if isNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+	}
+	return fmt.Errorf("%s%s对象失败: %v", action, s.name, err)
+}
+
+// URL 生成对象的访问地址，签名地址绑定存储桶主机，不使用 CDN 域名
+func (s *objectStore) URL(objectKey string, opts ...URLOption) (string, error) {
+	o := s.options(opts)
+	if o.process != "" {
+		return "", ErrProcessUnsupported
+	}
+	if o.signed {
+		return s.PresignGet(objectKey, PresignOptions{Expires: o.expires})
+	}
+	return withDomain(joinURL(s.publicURL, objectKey), o.domain)
+}
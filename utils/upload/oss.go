@@ -2,18 +2,38 @@
 package upload
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/zhanghaidi/zero-common/config"
 
 	"io"
+	"net/http"
+	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	// ossMaxCopySize CopyObject 支持的最大对象，更大的对象使用分片复制
+	ossMaxCopySize = 1024 * 1024 * 1024
+	// ossCopyPartSize 分片复制使用的分片大小
+	ossCopyPartSize = 100 * 1024 * 1024
+)
+
 type OssUploader struct {
-	bucket *oss.Bucket
+	urlResolver
+	bucket    *oss.Bucket
+	bucketURL string // 存储桶公开访问地址
+}
+
+func init() {
+	Register("oss", func(cfg config.StorageConf) (Uploader, error) {
+		return NewOssUploader(cfg)
+	})
 }
 
 func NewOssUploader(cfg config.StorageConf) (*OssUploader, error) {
@@ -27,14 +47,32 @@ func NewOssUploader(cfg config.StorageConf) (*OssUploader, error) {
 		return nil, fmt.Errorf("failed to get bucket: %w", err)
 	}
 
-	return &OssUploader{bucket: bucket}, nil
+	bucketURL := cfg.Oss.BucketURL
+	if bucketURL == "" {
+		// 未配置时使用默认的 https://<bucket>.<endpoint>
+		scheme, host, ok := strings.Cut(cfg.Oss.Endpoint, "://")
+		if !ok {
+			scheme, host = "https", cfg.Oss.Endpoint
+		}
+		bucketURL = scheme + "://" + cfg.Oss.BucketName + "." + strings.TrimSuffix(host, "/")
+	}
+
+	return &OssUploader{
+		urlResolver: urlResolver{cdnURL: cfg.CdnURL, private: cfg.Private},
+		bucket:      bucket,
+		bucketURL:   bucketURL,
+	}, nil
 }
 
-func (o *OssUploader) InitiateMultipartUpload(objectKey string) (string, string, error) {
+// InitiateMultipartUpload 初始化分片上传，会话由 OSS 保存，opts 中只有 WithObjectKey 生效
+func (o *OssUploader) InitiateMultipartUpload(objectKey string, opts ...MultipartOption) (string, string, error) {
+	return o.InitiateMultipartUploadCtx(context.Background(), objectKey, opts...)
+}
 
-	objectKey = fmt.Sprintf("chunk-upload/%d%s", time.Now().UnixNano(), strings.ToLower(path.Ext(objectKey)))
+func (o *OssUploader) InitiateMultipartUploadCtx(ctx context.Context, objectKey string, opts ...MultipartOption) (string, string, error) {
+	objectKey = multipartKey(objectKey, opts)
 
-	imur, err := o.bucket.InitiateMultipartUpload(objectKey)
+	imur, err := o.bucket.InitiateMultipartUpload(objectKey, oss.WithContext(ctx))
 	if err != nil {
 		return "", "", fmt.Errorf("failed to initiate multipart upload: %w", err)
 	}
@@ -42,21 +80,47 @@ func (o *OssUploader) InitiateMultipartUpload(objectKey string) (string, string,
 	return imur.UploadID, imur.Key, nil
 }
 
-func (o *OssUploader) UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64) (string, error) {
+// UploadPart 上传分片，MD5 由服务端通过 Content-MD5 校验，CRC64 在上传后按实际发送的内容校验，
+// 上传后校验失败时分片已写入服务端，会取消整个上传
+func (o *OssUploader) UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	return o.UploadPartCtx(context.Background(), objectKey, uploadID, partNumber, reader, partSize, opts...)
+}
+
+func (o *OssUploader) UploadPartCtx(ctx context.Context, objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
 	// 分片上传时，保持与初始化相同的路径
 	imur := oss.InitiateMultipartUploadResult{
 		Bucket:   o.bucket.BucketName,
 		Key:      objectKey,
 		UploadID: uploadID,
 	}
-	result, err := o.bucket.UploadPart(imur, reader, partSize, partNumber)
+	po := newPartOptions(opts)
+	options := []oss.Option{oss.WithContext(ctx)}
+	if po.contentMD5 != "" {
+		options = append(options, oss.ContentMD5(po.contentMD5))
+	}
+	hr := newHashReader(reader)
+	result, err := o.bucket.UploadPart(imur, hr, partSize, partNumber, options...)
 	if err != nil {
+		var se oss.ServiceError
+		if errors.As(err, &se) && (se.Code == "BadDigest" || se.Code == "InvalidDigest") {
+			return "", fmt.Errorf("%w: %v", ErrChecksumMismatch, err)
+		}
 		return "", fmt.Errorf("failed to upload part: %w", err)
 	}
+	if err = hr.verify(po); err != nil {
+		return "", abortMismatch(ctx, err, func(ctx context.Context) error {
+			return o.AbortMultipartUploadCtx(ctx, objectKey, uploadID)
+		})
+	}
 	return result.ETag, nil
 }
 
-func (o *OssUploader) CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (string, error) {
+// CompleteMultipartUpload 完成分片上传，分片 ETag 由服务端校验，大小与 CRC64 通过 HEAD 获取
+func (o *OssUploader) CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	return o.CompleteMultipartUploadCtx(context.Background(), objectKey, uploadID, parts)
+}
+
+func (o *OssUploader) CompleteMultipartUploadCtx(ctx context.Context, objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
 	var ossParts []oss.UploadPart
 	for _, part := range parts {
 		ossParts = append(ossParts, oss.UploadPart{
@@ -70,16 +134,115 @@ func (o *OssUploader) CompleteMultipartUpload(objectKey, uploadID string, parts
 		Key:      objectKey,
 		UploadID: uploadID,
 	}
-	_, err := o.bucket.CompleteMultipartUpload(imur, ossParts)
+	result, err := o.bucket.CompleteMultipartUpload(imur, ossParts, oss.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("failed to complete multipart upload: %w", err)
+		var se oss.ServiceError
+		if errors.As(err, &se) && se.Code == "InvalidPart" {
+			return nil, fmt.Errorf("%w: %v", ErrChecksumMismatch, err)
+		}
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
 	}
 
-	return objectKey, nil
+	header, err := o.bucket.GetObjectDetailedMeta(objectKey, oss.WithContext(ctx))
+	if err != nil {
+		return nil, ossError(objectKey, "获取", err)
+	}
+	size, _ := strconv.ParseInt(header.Get(oss.HTTPHeaderContentLength), 10, 64)
+	crc, _ := strconv.ParseUint(header.Get(oss.HTTPHeaderOssCRC64), 10, 64)
+	return &CompleteResult{ObjectKey: objectKey, Size: size, ETag: result.ETag, CRC64: crc}, nil
+}
+
+// ListMultipartUploads 分页列出存储桶中未完成的分片上传
+func (o *OssUploader) ListMultipartUploads() ([]MultipartUpload, error) {
+	return o.ListMultipartUploadsCtx(context.Background())
+}
+
+func (o *OssUploader) ListMultipartUploadsCtx(ctx context.Context) ([]MultipartUpload, error) {
+	var uploads []MultipartUpload
+	keyMarker, uploadIDMarker := "", ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res, err := o.bucket.ListMultipartUploads(oss.WithContext(ctx), oss.KeyMarker(keyMarker), oss.UploadIDMarker(uploadIDMarker))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+		}
+		for _, upload := range res.Uploads {
+			uploads = append(uploads, MultipartUpload{ObjectKey: upload.Key, UploadID: upload.UploadID, Initiated: upload.Initiated})
+		}
+		if !res.IsTruncated {
+			return uploads, nil
+		}
+		keyMarker, uploadIDMarker = res.NextKeyMarker, res.NextUploadIDMarker
+	}
+}
+
+// ListParts 列出已上传的分片
+func (o *OssUploader) ListParts(objectKey, uploadID string) ([]PartInfo, error) {
+	return o.ListPartsCtx(context.Background(), objectKey, uploadID)
+}
+
+func (o *OssUploader) ListPartsCtx(ctx context.Context, objectKey, uploadID string) ([]PartInfo, error) {
+	imur := oss.InitiateMultipartUploadResult{
+		Bucket:   o.bucket.BucketName,
+		Key:      objectKey,
+		UploadID: uploadID,
+	}
+
+	var parts []PartInfo
+	marker := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		options := []oss.Option{oss.WithContext(ctx)}
+		if marker > 0 {
+			options = append(options, oss.PartNumberMarker(marker))
+		}
+		res, err := o.bucket.ListUploadedParts(imur, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list parts: %w", err)
+		}
+		for _, part := range res.UploadedParts {
+			parts = append(parts, PartInfo{
+				PartNumber:   part.PartNumber,
+				ETag:         part.ETag,
+				Size:         int64(part.Size),
+				LastModified: part.LastModified,
+			})
+		}
+		next, _ := strconv.Atoi(res.NextPartNumberMarker)
+		if !res.IsTruncated || next <= marker {
+			return parts, nil
+		}
+		marker = next
+	}
+}
+
+// AbortMultipartUpload 取消分片上传，OSS 会删除已上传的分片
+func (o *OssUploader) AbortMultipartUpload(objectKey, uploadID string) error {
+	return o.AbortMultipartUploadCtx(context.Background(), objectKey, uploadID)
+}
+
+func (o *OssUploader) AbortMultipartUploadCtx(ctx context.Context, objectKey, uploadID string) error {
+	imur := oss.InitiateMultipartUploadResult{
+		Bucket:   o.bucket.BucketName,
+		Key:      objectKey,
+		UploadID: uploadID,
+	}
+	if err := o.bucket.AbortMultipartUpload(imur, oss.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
 }
 
 func (o *OssUploader) UploadFile(objectKey string, reader io.Reader) (string, error) {
-	if err := o.bucket.PutObject(objectKey, reader); err != nil {
+	return o.UploadFileCtx(context.Background(), objectKey, reader)
+}
+
+func (o *OssUploader) UploadFileCtx(ctx context.Context, objectKey string, reader io.Reader) (string, error) {
+	if err := o.bucket.PutObject(objectKey, reader, oss.WithContext(ctx)); err != nil {
 		return "", fmt.Errorf("failed to upload file: %w", err)
 	}
 	return objectKey, nil
@@ -87,10 +250,17 @@ func (o *OssUploader) UploadFile(objectKey string, reader io.Reader) (string, er
 
 // CopyFolder 复制OSS文件夹及其子文件到新路径
 func (o *OssUploader) CopyFolder(srcFolder, destFolder string) error {
+	return o.CopyFolderCtx(context.Background(), srcFolder, destFolder)
+}
+
+func (o *OssUploader) CopyFolderCtx(ctx context.Context, srcFolder, destFolder string) error {
 	marker := ""
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		// 列出源文件夹中的所有文件
-		lsRes, err := o.bucket.ListObjects(oss.Prefix(srcFolder), oss.Marker(marker))
+		lsRes, err := o.bucket.ListObjects(oss.Prefix(srcFolder), oss.Marker(marker), oss.WithContext(ctx))
 		if err != nil {
 			return fmt.Errorf("列出OSS对象失败: %v", err)
 		}
@@ -101,7 +271,7 @@ func (o *OssUploader) CopyFolder(srcFolder, destFolder string) error {
 			// 构造目标文件路径
 			destKey := strings.Replace(srcKey, srcFolder, destFolder, 1)
 			// 复制文件
-			_, err := o.bucket.CopyObject(srcKey, destKey)
+			_, err := o.bucket.CopyObject(srcKey, destKey, oss.WithContext(ctx))
 			if err != nil {
 				return fmt.Errorf("复制OSS对象失败: %v", err)
 			}
@@ -116,8 +286,33 @@ func (o *OssUploader) CopyFolder(srcFolder, destFolder string) error {
 	return nil
 }
 
+// CopyFile 复制单个OSS对象，超过 1GB 时使用分片复制
+func (o *OssUploader) CopyFile(srcKey, destKey string) error {
+	return o.CopyFileCtx(context.Background(), srcKey, destKey)
+}
+
+func (o *OssUploader) CopyFileCtx(ctx context.Context, srcKey, destKey string) error {
+	info, err := o.StatCtx(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	if info.Size <= ossMaxCopySize {
+		_, err = o.bucket.CopyObject(srcKey, destKey, oss.WithContext(ctx))
+	} else {
+		err = o.bucket.CopyFile(o.bucket.BucketName, srcKey, destKey, ossCopyPartSize, oss.WithContext(ctx))
+	}
+	if err != nil {
+		return fmt.Errorf("复制OSS对象失败: %v", err)
+	}
+	return nil
+}
+
 // DeleteFolder 删除OSS文件夹及其子文件，排除指定的子文件夹
 func (o *OssUploader) DeleteFolder(folderPath string, exclude ...string) error {
+	return o.DeleteFolderCtx(context.Background(), folderPath, exclude...)
+}
+
+func (o *OssUploader) DeleteFolderCtx(ctx context.Context, folderPath string, exclude ...string) error {
 	excludeSet := make(map[string]struct{})
 	for _, ex := range exclude {
 		excludeSet[ex] = struct{}{}
@@ -125,8 +320,11 @@ func (o *OssUploader) DeleteFolder(folderPath string, exclude ...string) error {
 
 	marker := ""
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		// 列出文件夹中的所有文件
-		lsRes, err := o.bucket.ListObjects(oss.Prefix(folderPath), oss.Marker(marker))
+		lsRes, err := o.bucket.ListObjects(oss.Prefix(folderPath), oss.Marker(marker), oss.WithContext(ctx))
 		if err != nil {
 			return fmt.Errorf("列出OSS对象失败: %v", err)
 		}
@@ -148,7 +346,7 @@ func (o *OssUploader) DeleteFolder(folderPath string, exclude ...string) error {
 
 		// 批量删除未排除的文件
 		if len(keys) > 0 {
-			_, err = o.bucket.DeleteObjects(keys)
+			_, err = o.bucket.DeleteObjects(keys, oss.WithContext(ctx))
 			if err != nil {
 				return fmt.Errorf("删除OSS对象失败: %v", err)
 			}
@@ -165,7 +363,11 @@ func (o *OssUploader) DeleteFolder(folderPath string, exclude ...string) error {
 
 // DeleteFile 删除OSS上的文件
 func (o *OssUploader) DeleteFile(objectKey string) error {
-	err := o.bucket.DeleteObject(objectKey) // 调用 OSS API 删除文件
+	return o.DeleteFileCtx(context.Background(), objectKey)
+}
+
+func (o *OssUploader) DeleteFileCtx(ctx context.Context, objectKey string) error {
+	err := o.bucket.DeleteObject(objectKey, oss.WithContext(ctx)) // 调用 OSS API 删除文件
 	if err != nil {
 		return fmt.Errorf("删除OSS文件失败: %v", err)
 	}
@@ -174,11 +376,18 @@ func (o *OssUploader) DeleteFile(objectKey string) error {
 
 // ListFiles 获取OSS存储桶目录下的所有文件列表，可以通过后缀进行过滤
 func (o *OssUploader) ListFiles(directory string, suffixFilters ...string) ([]string, error) {
+	return o.ListFilesCtx(context.Background(), directory, suffixFilters...)
+}
+
+func (o *OssUploader) ListFilesCtx(ctx context.Context, directory string, suffixFilters ...string) ([]string, error) {
 	var files []string
 	marker := ""
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		// 列出目录中的所有文件
-		lsRes, err := o.bucket.ListObjects(oss.Prefix(directory), oss.Marker(marker))
+		lsRes, err := o.bucket.ListObjects(oss.Prefix(directory), oss.Marker(marker), oss.WithContext(ctx))
 		if err != nil {
 			return nil, fmt.Errorf("列出OSS对象失败: %v", err)
 		}
@@ -205,3 +414,163 @@ func (o *OssUploader) ListFiles(directory string, suffixFilters ...string) ([]st
 	}
 	return files, nil
 }
+
+// ListObjects 分页列出OSS对象，按对象键排序
+func (o *OssUploader) ListObjects(ctx context.Context, opts ListOptions) (*ObjectPage, error) {
+	marker, err := decodeToken(opts.Token)
+	if err != nil {
+		return nil, err
+	}
+	options := []oss.Option{oss.WithContext(ctx), oss.Prefix(opts.Prefix), oss.Marker(marker), oss.MaxKeys(opts.limit())}
+	if opts.Delimiter != "" {
+		options = append(options, oss.Delimiter(opts.Delimiter))
+	}
+	lsRes, err := o.bucket.ListObjects(options...)
+	if err != nil {
+		return nil, fmt.Errorf("列出OSS对象失败: %v", err)
+	}
+
+	page := &ObjectPage{List: make([]ObjectInfo, 0, len(lsRes.Objects)+len(lsRes.CommonPrefixes))}
+	for _, object := range lsRes.Objects {
+		page.List = append(page.List, ObjectInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			ETag:         object.ETag,
+			LastModified: object.LastModified,
+		})
+	}
+	for _, prefix := range lsRes.CommonPrefixes {
+		page.List = append(page.List, ObjectInfo{Key: prefix, IsDir: true})
+	}
+	sortObjects(page.List)
+	if lsRes.IsTruncated {
+		page.NextToken = encodeToken(lsRes.NextMarker)
+	}
+	return page, nil
+}
+
+// PresignPut 生成直接上传的签名地址，OSS 的 PUT 签名不包含请求长度，无法限制上传大小
+func (o *OssUploader) PresignPut(objectKey string, opts PresignOptions) (string, error) {
+	if opts.MaxSize > 0 {
+		return "", ErrPresignMaxSize
+	}
+	var options []oss.Option
+	if opts.ContentType != "" {
+		options = append(options, oss.ContentType(opts.ContentType))
+	}
+
+	u, err := o.bucket.SignURL(objectKey, oss.HTTPPut, int64(opts.expires()/time.Second), options...)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign url: %w", err)
+	}
+	return u, nil
+}
+
+// PresignGet 生成直接下载的签名地址
+func (o *OssUploader) PresignGet(objectKey string, opts PresignOptions) (string, error) {
+	u, err := o.bucket.SignURL(objectKey, oss.HTTPGet, int64(opts.expires()/time.Second))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign url: %w", err)
+	}
+	return u, nil
+}
+
+// Open 读取OSS对象，r 不为 nil 时只读取指定范围
+func (o *OssUploader) Open(objectKey string, r *Range) (io.ReadCloser, error) {
+	return o.OpenCtx(context.Background(), objectKey, r)
+}
+
+func (o *OssUploader) OpenCtx(ctx context.Context, objectKey string, r *Range) (io.ReadCloser, error) {
+	options := []oss.Option{oss.WithContext(ctx)}
+	if r != nil {
+		options = append(options, oss.NormalizedRange(strings.TrimPrefix(r.header(), "bytes=")))
+	}
+	body, err := o.bucket.GetObject(objectKey, options...)
+	if err != nil {
+		return nil, ossError(objectKey, "读取", err)
+	}
+	return body, nil
+}
+
+// Download 将OSS对象保存到本地文件
+func (o *OssUploader) Download(objectKey, filePath string, r *Range) error {
+	return o.DownloadCtx(context.Background(), objectKey, filePath, r)
+}
+
+func (o *OssUploader) DownloadCtx(ctx context.Context, objectKey, filePath string, r *Range) error {
+	body, err := o.OpenCtx(ctx, objectKey, r)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return saveFile(ctx, filePath, body)
+}
+
+// Stat 获取OSS对象元数据
+func (o *OssUploader) Stat(objectKey string) (*ObjectInfo, error) {
+	return o.StatCtx(context.Background(), objectKey)
+}
+
+func (o *OssUploader) StatCtx(ctx context.Context, objectKey string) (*ObjectInfo, error) {
+	header, err := o.bucket.GetObjectDetailedMeta(objectKey, oss.WithContext(ctx))
+	if err != nil {
+		return nil, ossError(objectKey, "获取", err)
+	}
+
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	lastModified, _ := http.ParseTime(header.Get("Last-Modified"))
+	return &ObjectInfo{
+		Key:          objectKey,
+		Size:         size,
+		ContentType:  header.Get("Content-Type"),
+		ETag:         header.Get("ETag"),
+		LastModified: lastModified,
+	}, nil
+}
+
+// Exists 判断OSS对象是否存在
+func (o *OssUploader) Exists(objectKey string) (bool, error) {
+	return o.ExistsCtx(context.Background(), objectKey)
+}
+
+func (o *OssUploader) ExistsCtx(ctx context.Context, objectKey string) (bool, error) {
+	exists, err := o.bucket.IsObjectExist(objectKey, oss.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("获取OSS对象失败: %v", err)
+	}
+	return exists, nil
+}
+
+// ossError 对象不存在时转换为 ErrObjectNotFound
+func ossError(objectKey, action string, err error) error {
+	var se oss.ServiceError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+	}
+	return fmt.Errorf("%sOSS对象失败: %v", action, err)
+}
+
+// URL 生成OSS对象的访问地址，OSS 签名不包含主机，签名地址同样可以使用 CDN 域名
+func (o *OssUploader) URL(objectKey string, opts ...URLOption) (string, error) {
+	uo := o.options(opts)
+
+	var rawURL string
+	if uo.signed {
+		var options []oss.Option
+		if uo.process != "" {
+			options = append(options, oss.Process(uo.process))
+		}
+		u, err := o.bucket.SignURL(objectKey, oss.HTTPGet, int64(uo.expires/time.Second), options...)
+		if err != nil {
+			return "", fmt.Errorf("failed to sign url: %w", err)
+		}
+		rawURL = u
+	} else {
+		rawURL = joinURL(o.bucketURL, objectKey)
+		if uo.process != "" {
+			rawURL += "?x-oss-process=" + url.QueryEscape(uo.process)
+		}
+	}
+	return withDomain(rawURL, uo.domain)
+}
@@ -0,0 +1,59 @@
+package upload
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+func TestOssUploaderURL(t *testing.T) {
+	var cfg config.StorageConf
+	cfg.Oss.Endpoint = "oss-cn-hangzhou.aliyuncs.com"
+	cfg.Oss.AccessKeyID = "ak"
+	cfg.Oss.AccessKeySecret = "sk"
+	cfg.Oss.BucketName = "bucket"
+
+	u, err := NewOssUploader(cfg)
+	if err != nil {
+		t.Fatalf("NewOssUploader() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts []URLOption
+		want string
+	}{
+		{"default", nil, "https://bucket.oss-cn-hangzhou.aliyuncs.com/img/a%20b.png"},
+		{"process", []URLOption{WithProcess("image/resize,w_200")}, "https://bucket.oss-cn-hangzhou.aliyuncs.com/img/a%20b.png?x-oss-process=image%2Fresize%2Cw_200"},
+		{"cdn", []URLOption{WithDomain("cdn.example.com")}, "https://cdn.example.com/img/a%20b.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.URL("img/a b.png", tt.opts...)
+			if err != nil || got != tt.want {
+				t.Errorf("URL() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	signed, err := u.URL("img/a.png", WithExpires(time.Minute), WithProcess("image/resize,w_200"), WithDomain("http://cdn.example.com"))
+	if err != nil {
+		t.Fatalf("URL() signed error = %v", err)
+	}
+	parsed, _ := url.Parse(signed)
+	if parsed.Host != "cdn.example.com" || parsed.Query().Get("Signature") == "" || parsed.Query().Get("x-oss-process") != "image/resize,w_200" {
+		t.Errorf("URL() signed = %q", signed)
+	}
+
+	cfg.Oss.BucketURL = "https://static.example.com"
+	cfg.Private = true
+	if u, err = NewOssUploader(cfg); err != nil {
+		t.Fatalf("NewOssUploader() error = %v", err)
+	}
+	if got, _ := u.URL("img/a.png"); !strings.Contains(got, "bucket.oss-cn-hangzhou.aliyuncs.com/") || !strings.Contains(got, "Signature=") {
+		t.Errorf("URL() for private bucket = %q", got)
+	}
+}
@@ -0,0 +1,340 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/zhanghaidi/zero-common/config"
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+)
+
+// 上传校验错误码，取值与 HTTP 413、415 对应
+const (
+	CodeFileTooLarge        = 41300
+	CodeExtensionNotAllowed = 41500
+	CodeMimeNotAllowed      = 41501
+	CodeMimeMismatch        = 41502
+)
+
+var (
+	ErrFileTooLarge        = errorx.NewCodeError(CodeFileTooLarge, "文件大小超出限制")
+	ErrExtensionNotAllowed = errorx.NewCodeError(CodeExtensionNotAllowed, "不支持的文件扩展名")
+	ErrMimeNotAllowed      = errorx.NewCodeError(CodeMimeNotAllowed, "不支持的文件类型")
+	ErrMimeMismatch        = errorx.NewCodeError(CodeMimeMismatch, "文件内容与扩展名不符")
+)
+
+// sniffLen 识别文件类型读取的字节数，与 http.DetectContentType 一致
+const sniffLen = 512
+
+// strictMimes 可以通过文件头可靠识别的类型，扩展名对应这些类型时文件头必须一致
+var strictMimes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/bmp":       true,
+	"application/pdf": true,
+	"application/zip": true,
+}
+
+// executableSignatures 可执行文件的文件头，http.DetectContentType 无法识别
+var executableSignatures = []struct {
+	magic       []byte
+	contentType string
+}{
+	{[]byte("MZ"), "application/x-msdownload"},
+	{[]byte("\x7fELF"), "application/x-elf"},
+	{[]byte("\xfe\xed\xfa\xce"), "application/x-mach-binary"},
+	{[]byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary"},
+	{[]byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+}
+
+// PolicyUploader 在 Uploader 外层校验文件大小、扩展名与文件头识别的 MIME 类型，校验失败返回 errorx.CodeError
+type PolicyUploader struct {
+	Uploader
+	conf config.UploadPolicyConf
+}
+
+// NewPolicyUploader 使用校验策略包装上传器
+func NewPolicyUploader(uploader Uploader, conf config.UploadPolicyConf) *PolicyUploader {
+	return &PolicyUploader{Uploader: uploader, conf: conf}
+}
+
+// Unwrap 返回被包装的上传器
+func (p *PolicyUploader) Unwrap() Uploader {
+	return p.Uploader
+}
+
+// InitiateMultipartUpload 校验扩展名，WithObjectKey 指定对象键时校验其扩展名，通过 WithObjectSize 声明的大小超出限制时直接拒绝
+func (p *PolicyUploader) InitiateMultipartUpload(objectKey string, opts ...MultipartOption) (string, string, error) {
+	return p.InitiateMultipartUploadCtx(context.Background(), objectKey, opts...)
+}
+
+func (p *PolicyUploader) InitiateMultipartUploadCtx(ctx context.Context, objectKey string, opts ...MultipartOption) (string, string, error) {
+	var session UploadSession
+	for _, opt := range opts {
+		opt(&session)
+	}
+	if session.ObjectKey != "" {
+		objectKey = session.ObjectKey
+	}
+	if err := p.checkExt(objectKey); err != nil {
+		return "", "", err
+	}
+	if err := p.checkSize(session.ObjectSize); err != nil {
+		return "", "", err
+	}
+	return p.Uploader.InitiateMultipartUploadCtx(ctx, objectKey, opts...)
+}
+
+// UploadPart 校验分片大小，第一个分片额外校验文件头
+func (p *PolicyUploader) UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	return p.UploadPartCtx(context.Background(), objectKey, uploadID, partNumber, reader, partSize, opts...)
+}
+
+func (p *PolicyUploader) UploadPartCtx(ctx context.Context, objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	if err := p.checkSize(partSize); err != nil {
+		return "", err
+	}
+	if partNumber == 1 {
+		br := bufio.NewReaderSize(reader, sniffLen)
+		head, _ := br.Peek(sniffLen)
+		if err := p.checkContent(objectKey, head); err != nil {
+			return "", err
+		}
+		reader = br
+	}
+	return p.Uploader.UploadPartCtx(ctx, objectKey, uploadID, partNumber, reader, partSize, opts...)
+}
+
+// CompleteMultipartUpload 合并前校验分片总大小，超出限制时取消上传
+func (p *PolicyUploader) CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	return p.CompleteMultipartUploadCtx(context.Background(), objectKey, uploadID, parts)
+}
+
+func (p *PolicyUploader) CompleteMultipartUploadCtx(ctx context.Context, objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	if p.conf.MaxSize > 0 {
+		uploaded, err := p.Uploader.ListPartsCtx(ctx, objectKey, uploadID)
+		if err != nil {
+			return nil, err
+		}
+		completing := make(map[int]bool, len(parts))
+		for _, part := range parts {
+			completing[part.PartNumber] = true
+		}
+		var total int64
+		for _, part := range uploaded {
+			if completing[part.PartNumber] {
+				total += part.Size
+			}
+		}
+		if total > p.conf.MaxSize {
+			_ = p.Uploader.AbortMultipartUploadCtx(ctx, objectKey, uploadID)
+			return nil, ErrFileTooLarge
+		}
+	}
+	return p.Uploader.CompleteMultipartUploadCtx(ctx, objectKey, uploadID, parts)
+}
+
+// UploadFile 校验扩展名与文件头，上传过程中超出大小限制时返回 ErrFileTooLarge，同名的已有对象保持不变
+func (p *PolicyUploader) UploadFile(objectKey string, reader io.Reader) (string, error) {
+	return p.UploadFileCtx(context.Background(), objectKey, reader)
+}
+
+func (p *PolicyUploader) UploadFileCtx(ctx context.Context, objectKey string, reader io.Reader) (string, error) {
+	if err := p.checkExt(objectKey); err != nil {
+		return "", err
+	}
+	br := bufio.NewReaderSize(reader, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	if err := p.checkContent(objectKey, head); err != nil {
+		return "", err
+	}
+
+	var r io.Reader = br
+	var limited *maxSizeReader
+	if p.conf.MaxSize > 0 {
+		limited = &maxSizeReader{reader: br, remaining: p.conf.MaxSize}
+		r = limited
+	}
+	key, err := p.Uploader.UploadFileCtx(ctx, objectKey, r)
+	if limited != nil && limited.exceeded {
+		// 驱动未把读取错误返回时对象已被截断写入，此时才需要删除
+		if err == nil {
+			_ = p.Uploader.DeleteFileCtx(ctx, objectKey)
+		}
+		return "", ErrFileTooLarge
+	}
+	return key, err
+}
+
+// CopyFile 校验目标的扩展名，配置了大小限制时校验源对象的大小
+func (p *PolicyUploader) CopyFile(srcKey, destKey string) error {
+	return p.CopyFileCtx(context.Background(), srcKey, destKey)
+}
+
+func (p *PolicyUploader) CopyFileCtx(ctx context.Context, srcKey, destKey string) error {
+	if err := p.checkExt(destKey); err != nil {
+		return err
+	}
+	if p.conf.MaxSize > 0 {
+		info, err := p.Uploader.StatCtx(ctx, srcKey)
+		if err != nil {
+			return err
+		}
+		if err = p.checkSize(info.Size); err != nil {
+			return err
+		}
+	}
+	return p.Uploader.CopyFileCtx(ctx, srcKey, destKey)
+}
+
+// PresignPut 校验扩展名与 Content-Type，并将大小限制写入预签名地址。
+// 调用方未指定 MaxSize 且驱动无法限制大小时，生成不限制大小的地址，客户端上传完成后需调用 CheckUploaded 校验
+func (p *PolicyUploader) PresignPut(objectKey string, opts PresignOptions) (string, error) {
+	if err := p.checkExt(objectKey); err != nil {
+		return "", err
+	}
+	if opts.ContentType != "" && !p.allowedMime(baseMime(opts.ContentType)) {
+		return "", ErrMimeNotAllowed
+	}
+	if p.conf.MaxSize <= 0 || (opts.MaxSize > 0 && opts.MaxSize <= p.conf.MaxSize) {
+		return p.Uploader.PresignPut(objectKey, opts)
+	}
+
+	requested := opts.MaxSize
+	opts.MaxSize = p.conf.MaxSize
+	rawURL, err := p.Uploader.PresignPut(objectKey, opts)
+	if errors.Is(err, ErrPresignMaxSize) && requested == 0 {
+		opts.MaxSize = 0
+		return p.Uploader.PresignPut(objectKey, opts)
+	}
+	return rawURL, err
+}
+
+// CheckUploaded 校验通过预签名地址上传的对象大小，超出限制时删除对象并返回 ErrFileTooLarge
+func (p *PolicyUploader) CheckUploaded(ctx context.Context, objectKey string) (*ObjectInfo, error) {
+	info, err := p.Uploader.StatCtx(ctx, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.checkSize(info.Size); err != nil {
+		_ = p.Uploader.DeleteFileCtx(ctx, objectKey)
+		return nil, err
+	}
+	return info, nil
+}
+
+func (p *PolicyUploader) checkSize(size int64) error {
+	if p.conf.MaxSize > 0 && size > p.conf.MaxSize {
+		return ErrFileTooLarge
+	}
+	return nil
+}
+
+func (p *PolicyUploader) checkExt(objectKey string) error {
+	if len(p.conf.AllowedExts) == 0 {
+		return nil
+	}
+	ext := strings.ToLower(path.Ext(objectKey))
+	for _, allowed := range p.conf.AllowedExts {
+		if ext != "" && strings.EqualFold(ext, allowed) {
+			return nil
+		}
+	}
+	return ErrExtensionNotAllowed
+}
+
+// checkContent 根据文件头识别类型，校验与扩展名是否一致以及是否在允许的类型中
+func (p *PolicyUploader) checkContent(objectKey string, head []byte) error {
+	expected := baseMime(mime.TypeByExtension(path.Ext(objectKey)))
+	detected := detectContentType(head)
+
+	if detected != expected {
+		// 扩展名声明为可识别的类型，但文件头不符，如 .exe 改名为 .jpg
+		if strictMimes[expected] || isExecutable(detected) {
+			return ErrMimeMismatch
+		}
+	}
+
+	// 文件头无法识别具体类型时，以扩展名对应的类型为准
+	contentType := detected
+	if expected != "" && (detected == "application/octet-stream" || strings.HasPrefix(detected, "text/")) {
+		contentType = expected
+	}
+	if !p.allowedMime(contentType) {
+		return ErrMimeNotAllowed
+	}
+	return nil
+}
+
+func (p *PolicyUploader) allowedMime(contentType string) bool {
+	if len(p.conf.AllowedMimes) == 0 {
+		return true
+	}
+	for _, allowed := range p.conf.AllowedMimes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(contentType, prefix+"/") {
+				return true
+			}
+		} else if strings.EqualFold(contentType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectContentType 识别文件头对应的 MIME 类型，不含参数
+func detectContentType(head []byte) string {
+	for _, sig := range executableSignatures {
+		if bytes.HasPrefix(head, sig.magic) {
+			return sig.contentType
+		}
+	}
+	return baseMime(http.DetectContentType(head))
+}
+
+func isExecutable(contentType string) bool {
+	for _, sig := range executableSignatures {
+		if sig.contentType == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// baseMime 去掉 MIME 类型中的参数，如 text/plain; charset=utf-8
+func baseMime(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return strings.TrimSpace(strings.ToLower(contentType))
+}
+
+// maxSizeReader 读取超过 remaining 字节时返回 ErrFileTooLarge
+type maxSizeReader struct {
+	reader    io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	if int64(n) > r.remaining {
+		n, r.remaining, r.exceeded = int(r.remaining), 0, true
+		return n, ErrFileTooLarge
+	}
+	r.remaining -= int64(n)
+	return n, err
+}
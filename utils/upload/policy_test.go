@@ -0,0 +1,161 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zhanghaidi/zero-common/config"
+	"github.com/zhanghaidi/zero-common/utils/errorx"
+)
+
+var (
+	pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	exeHeader = []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00")
+)
+
+func TestPolicyUploaderUploadFile(t *testing.T) {
+	dir := t.TempDir()
+	u := NewPolicyUploader(NewLocalUploader(dir), config.UploadPolicyConf{
+		MaxSize:      32,
+		AllowedExts:  []string{".png", ".JPG", ".txt", ".mp4"},
+		AllowedMimes: []string{"image/*", "text/plain"},
+	})
+
+	tests := []struct {
+		name    string
+		key     string
+		content []byte
+		want    error
+	}{
+		{"png", "a.png", pngHeader, nil},
+		{"text", "a.txt", []byte("hello"), nil},
+		{"extension", "a.gif", []byte("GIF89a"), ErrExtensionNotAllowed},
+		{"no extension", "a", []byte("hello"), ErrExtensionNotAllowed},
+		{"exe renamed to jpg", "a.jpg", exeHeader, ErrMimeMismatch},
+		{"exe renamed to txt", "b.txt", exeHeader, ErrMimeMismatch},
+		{"text renamed to png", "b.png", []byte("hello"), ErrMimeMismatch},
+		{"mime", "a.mp4", []byte("plain"), ErrMimeNotAllowed},
+		{"size", "c.txt", bytes.Repeat([]byte("a"), 33), ErrFileTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := u.UploadFile(tt.key, bytes.NewReader(tt.content))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("UploadFile() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil {
+				testRead(t, u, tt.key, string(tt.content))
+				return
+			}
+			var codeErr *errorx.CodeError
+			if !errors.As(err, &codeErr) {
+				t.Errorf("UploadFile() error = %T, want *errorx.CodeError", err)
+			}
+			if ok, _ := u.Exists(tt.key); ok {
+				t.Errorf("rejected file %q should not be stored", tt.key)
+			}
+		})
+	}
+
+	// 超出大小的上传不影响同名的已有对象
+	if _, err := u.UploadFile("a.txt", bytes.NewReader(bytes.Repeat([]byte("a"), 33))); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("UploadFile() overwrite error = %v, want ErrFileTooLarge", err)
+	}
+	testRead(t, u, "a.txt", "hello")
+}
+
+func TestPolicyUploaderMultipart(t *testing.T) {
+	u := NewPolicyUploader(NewLocalUploader(t.TempDir()), config.UploadPolicyConf{
+		MaxSize:     20,
+		AllowedExts: []string{".png"},
+	})
+
+	if _, _, err := u.InitiateMultipartUpload("a.exe"); !errors.Is(err, ErrExtensionNotAllowed) {
+		t.Errorf("InitiateMultipartUpload(a.exe) error = %v", err)
+	}
+	if _, _, err := u.InitiateMultipartUpload("a.png", WithObjectSize(21)); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("InitiateMultipartUpload() with large size error = %v", err)
+	}
+
+	uploadID, objectKey, err := u.InitiateMultipartUpload("a.png")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	if _, err = u.UploadPart(objectKey, uploadID, 1, bytes.NewReader(exeHeader), int64(len(exeHeader))); !errors.Is(err, ErrMimeMismatch) {
+		t.Errorf("UploadPart() with exe header error = %v", err)
+	}
+	if _, err = u.UploadPart(objectKey, uploadID, 2, strings.NewReader(strings.Repeat("a", 21)), 21); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("UploadPart() with large part error = %v", err)
+	}
+
+	var parts []Part
+	for n, chunk := range [][]byte{pngHeader, []byte("0123456789")} {
+		etag, err := u.UploadPart(objectKey, uploadID, n+1, bytes.NewReader(chunk), int64(len(chunk)))
+		if err != nil {
+			t.Fatalf("UploadPart(%d) error = %v", n+1, err)
+		}
+		parts = append(parts, Part{ETag: etag, PartNumber: n + 1})
+	}
+	if _, err = u.CompleteMultipartUpload(objectKey, uploadID, parts); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("CompleteMultipartUpload() error = %v, want ErrFileTooLarge", err)
+	}
+	if _, err = u.ListParts(objectKey, uploadID); err == nil {
+		t.Error("oversized upload should be aborted")
+	}
+}
+
+func TestPolicyUploaderPresignPut(t *testing.T) {
+	local := NewLocalUploader(t.TempDir(), WithBaseURL("http://localhost/files"), WithSigningKey("secret"))
+	u := NewPolicyUploader(local, config.UploadPolicyConf{
+		MaxSize:      100,
+		AllowedExts:  []string{".png"},
+		AllowedMimes: []string{"image/*"},
+	})
+
+	if _, err := u.PresignPut("a.exe", PresignOptions{}); !errors.Is(err, ErrExtensionNotAllowed) {
+		t.Errorf("PresignPut(a.exe) error = %v", err)
+	}
+	if _, err := u.PresignPut("a.png", PresignOptions{ContentType: "application/x-msdownload"}); !errors.Is(err, ErrMimeNotAllowed) {
+		t.Errorf("PresignPut() with content type error = %v", err)
+	}
+	rawURL, err := u.PresignPut("a.png", PresignOptions{ContentType: "image/png", MaxSize: 1000})
+	if err != nil {
+		t.Fatalf("PresignPut() error = %v", err)
+	}
+	if !strings.Contains(rawURL, "max-size=100&") {
+		t.Errorf("PresignPut() = %s, want max-size limited by policy", rawURL)
+	}
+	// 未指定 MaxSize 时使用策略的大小限制
+	if rawURL, err = u.PresignPut("a.png", PresignOptions{}); err != nil || !strings.Contains(rawURL, "max-size=100&") {
+		t.Errorf("PresignPut() without MaxSize = %s, %v", rawURL, err)
+	}
+
+	ctx := context.Background()
+	mustUpload(t, local, "a.png", string(pngHeader))
+	if info, err := u.CheckUploaded(ctx, "a.png"); err != nil || info.Size != int64(len(pngHeader)) {
+		t.Errorf("CheckUploaded() = %+v, %v", info, err)
+	}
+	mustUpload(t, local, "b.png", string(bytes.Repeat([]byte("a"), 101)))
+	if _, err = u.CheckUploaded(ctx, "b.png"); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("CheckUploaded() error = %v, want ErrFileTooLarge", err)
+	}
+	if ok, _ := local.Exists("b.png"); ok {
+		t.Error("oversized object should be deleted")
+	}
+}
+
+func TestPolicyUploaderPresignPutS3(t *testing.T) {
+	s3, _ := newTestS3Uploader(t)
+	u := NewPolicyUploader(s3, config.UploadPolicyConf{MaxSize: 100})
+	// 驱动无法限制大小时生成不限制大小的地址，上传后由 CheckUploaded 校验
+	if _, err := u.PresignPut("a.png", PresignOptions{}); err != nil {
+		t.Errorf("PresignPut() error = %v", err)
+	}
+	// 调用方明确要求限制大小时不能忽略
+	if _, err := u.PresignPut("a.png", PresignOptions{MaxSize: 10}); !errors.Is(err, ErrPresignMaxSize) {
+		t.Errorf("PresignPut() with MaxSize error = %v, want ErrPresignMaxSize", err)
+	}
+}
@@ -0,0 +1,778 @@
+// upload/qiniu.go
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+const (
+	// qiniuPartSize UploadFile 在数据长度未知时使用的分片大小，七牛分片上传要求分片不小于 1MB
+	qiniuPartSize = 16 * 1024 * 1024
+	// qiniuTokenExpiration 上传凭证的有效期
+	qiniuTokenExpiration = time.Hour
+	// qiniuBatchSize 单次批量操作的最大条数
+	qiniuBatchSize = 1000
+	// qiniuListLimit 单次列举的最大条数
+	qiniuListLimit = 1000
+)
+
+// QiniuUploader 七牛云对象存储 Kodo，上传使用分片上传 v2 接口，管理操作使用 rs/rsf 接口
+type QiniuUploader struct {
+	urlResolver
+	bucket    string
+	accessKey string
+	secretKey string
+	upHost    string
+	rsHost    string
+	rsfHost   string
+	domain    string // 存储桶绑定的访问域名，用于生成下载地址
+	client    *http.Client
+	now       func() time.Time
+}
+
+// qiniuError 七牛接口返回的错误
+type qiniuError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *qiniuError) Error() string {
+	return fmt.Sprintf("qiniu error: status=%d message=%s", e.StatusCode, e.Message)
+}
+
+// qiniuNotFound 七牛资源不存在时返回的状态码
+const qiniuNotFound = 612
+
+// isQiniuNotFound 判断错误是否为对象不存在
+func isQiniuNotFound(err error) bool {
+	var qe *qiniuError
+	return errors.As(err, &qe) && (qe.StatusCode == qiniuNotFound || qe.StatusCode == http.StatusNotFound)
+}
+
+type qiniuListItem struct {
+	Key      string `json:"key"`
+	Fsize    int64  `json:"fsize"`
+	Hash     string `json:"hash"`
+	MimeType string `json:"mimeType"`
+	PutTime  int64  `json:"putTime"`
+}
+
+type qiniuListResult struct {
+	Marker         string          `json:"marker"`
+	Items          []qiniuListItem `json:"items"`
+	CommonPrefixes []string        `json:"commonPrefixes"`
+}
+
+type qiniuPart struct {
+	PartNumber int    `json:"partNumber"`
+	Etag       string `json:"etag"`
+}
+
+func init() {
+	Register("qiniu", func(cfg config.StorageConf) (Uploader, error) {
+		return NewQiniuUploader(cfg)
+	})
+}
+
+func NewQiniuUploader(cfg config.StorageConf) (*QiniuUploader, error) {
+	c := cfg.Qiniu
+	if c.BucketName == "" {
+		return nil, errors.New("failed to create Qiniu client: bucket name is required")
+	}
+
+	return &QiniuUploader{
+		urlResolver: urlResolver{cdnURL: cfg.CdnURL, private: cfg.Private},
+		bucket:      c.BucketName,
+		accessKey:   c.AccessKey,
+		secretKey:   c.SecretKey,
+		upHost:      qiniuHost(c.UpHost, "https://up.qiniup.com"),
+		rsHost:      qiniuHost(c.RsHost, "https://rs.qiniuapi.com"),
+		rsfHost:     qiniuHost(c.RsfHost, "https://rsf.qiniuapi.com"),
+		domain:      c.BucketURL,
+		client:      &http.Client{},
+		now:         time.Now,
+	}, nil
+}
+
+func qiniuHost(host, fallback string) string {
+	if host == "" {
+		host = fallback
+	}
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// InitiateMultipartUpload 初始化分片上传，会话由七牛保存，opts 中只有 WithObjectKey 生效
+func (q *QiniuUploader) InitiateMultipartUpload(objectKey string, opts ...MultipartOption) (string, string, error) {
+	return q.InitiateMultipartUploadCtx(context.Background(), objectKey, opts...)
+}
+
+func (q *QiniuUploader) InitiateMultipartUploadCtx(ctx context.Context, objectKey string, opts ...MultipartOption) (string, string, error) {
+	objectKey = multipartKey(objectKey, opts)
+
+	uploadID, err := q.initiateMultipartUpload(ctx, objectKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to initiate multipart upload: %w", err)
+	}
+	return uploadID, objectKey, nil
+}
+
+// UploadPart 上传分片，不发送 Content-MD5，opts 提供的 MD5 与 CRC64 都只在上传后按实际发送的内容校验，
+// 校验失败时分片已写入七牛，会取消整个上传
+func (q *QiniuUploader) UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	return q.UploadPartCtx(context.Background(), objectKey, uploadID, partNumber, reader, partSize, opts...)
+}
+
+func (q *QiniuUploader) UploadPartCtx(ctx context.Context, objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error) {
+	hr := newHashReader(reader)
+	etag, err := q.uploadPart(ctx, objectKey, uploadID, partNumber, hr, partSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload part: %w", err)
+	}
+	if err = hr.verify(newPartOptions(opts)); err != nil {
+		return "", abortMismatch(ctx, err, func(ctx context.Context) error {
+			return q.AbortMultipartUploadCtx(ctx, objectKey, uploadID)
+		})
+	}
+	return etag, nil
+}
+
+// CompleteMultipartUpload 完成分片上传，分片 ETag 由七牛校验，返回的 ETag 为七牛的 qetag
+func (q *QiniuUploader) CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	return q.CompleteMultipartUploadCtx(context.Background(), objectKey, uploadID, parts)
+}
+
+func (q *QiniuUploader) CompleteMultipartUploadCtx(ctx context.Context, objectKey, uploadID string, parts []Part) (*CompleteResult, error) {
+	if err := q.completeMultipartUpload(ctx, objectKey, uploadID, parts); err != nil {
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	info, err := q.StatCtx(ctx, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	return &CompleteResult{ObjectKey: objectKey, Size: info.Size, ETag: info.ETag}, nil
+}
+
+// ListParts 列出已上传的分片
+func (q *QiniuUploader) ListParts(objectKey, uploadID string) ([]PartInfo, error) {
+	return q.ListPartsCtx(context.Background(), objectKey, uploadID)
+}
+
+func (q *QiniuUploader) ListPartsCtx(ctx context.Context, objectKey, uploadID string) ([]PartInfo, error) {
+	parts, err := q.listParts(ctx, objectKey, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+	return parts, nil
+}
+
+// AbortMultipartUpload 取消分片上传
+func (q *QiniuUploader) AbortMultipartUpload(objectKey, uploadID string) error {
+	return q.AbortMultipartUploadCtx(context.Background(), objectKey, uploadID)
+}
+
+func (q *QiniuUploader) AbortMultipartUploadCtx(ctx context.Context, objectKey, uploadID string) error {
+	if err := q.abortMultipartUpload(ctx, objectKey, uploadID); err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+// ListMultipartUploads 七牛没有列举分片上传的接口，返回 ErrListUploadsUnsupported，未完成的上传由七牛在 7 天后自动清理
+func (q *QiniuUploader) ListMultipartUploads() ([]MultipartUpload, error) {
+	return q.ListMultipartUploadsCtx(context.Background())
+}
+
+func (q *QiniuUploader) ListMultipartUploadsCtx(ctx context.Context) ([]MultipartUpload, error) {
+	return nil, ErrListUploadsUnsupported
+}
+
+// UploadFile 直接上传文件，超过一个分片大小时自动转为分片上传
+func (q *QiniuUploader) UploadFile(objectKey string, reader io.Reader) (string, error) {
+	return q.UploadFileCtx(context.Background(), objectKey, reader)
+}
+
+func (q *QiniuUploader) UploadFileCtx(ctx context.Context, objectKey string, reader io.Reader) (string, error) {
+	if err := q.uploadStream(ctx, objectKey, reader, qiniuPartSize); err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	return objectKey, nil
+}
+
+// CopyFolder 复制文件夹及其子文件到新路径
+func (q *QiniuUploader) CopyFolder(srcFolder, destFolder string) error {
+	return q.CopyFolderCtx(context.Background(), srcFolder, destFolder)
+}
+
+func (q *QiniuUploader) CopyFolderCtx(ctx context.Context, srcFolder, destFolder string) error {
+	return q.walk(ctx, srcFolder, func(items []qiniuListItem) error {
+		ops := make([]string, 0, len(items))
+		for _, item := range items {
+			destKey := strings.Replace(item.Key, srcFolder, destFolder, 1)
+			ops = append(ops, "/copy/"+q.entry(item.Key)+"/"+q.entry(destKey)+"/force/true")
+		}
+		if err := q.batch(ctx, ops); err != nil {
+			return fmt.Errorf("复制七牛对象失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// CopyFile 复制单个对象，目标已存在时覆盖
+func (q *QiniuUploader) CopyFile(srcKey, destKey string) error {
+	return q.CopyFileCtx(context.Background(), srcKey, destKey)
+}
+
+func (q *QiniuUploader) CopyFileCtx(ctx context.Context, srcKey, destKey string) error {
+	if err := q.doManage(ctx, http.MethodPost, q.rsHost+"/copy/"+q.entry(srcKey)+"/"+q.entry(destKey)+"/force/true", nil, nil); err != nil {
+		return qiniuObjectError(srcKey, "复制", err)
+	}
+	return nil
+}
+
+// DeleteFolder 删除文件夹及其子文件，排除指定的子文件夹
+func (q *QiniuUploader) DeleteFolder(folderPath string, exclude ...string) error {
+	return q.DeleteFolderCtx(context.Background(), folderPath, exclude...)
+}
+
+func (q *QiniuUploader) DeleteFolderCtx(ctx context.Context, folderPath string, exclude ...string) error {
+
+	// 先收集再删除，避免删除过程中影响分页
+	var ops []string
+	err := q.walk(ctx, folderPath, func(items []qiniuListItem) error {
+		for _, item := range items {
+			if !isExcluded(item.Key, folderPath, exclude) {
+				ops = append(ops, "/delete/"+q.entry(item.Key))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("列出七牛对象失败: %v", err)
+	}
+
+	if err = q.batch(ctx, ops); err != nil {
+		return fmt.Errorf("删除七牛对象失败: %v", err)
+	}
+	return nil
+}
+
+// DeleteFile 删除文件
+func (q *QiniuUploader) DeleteFile(objectKey string) error {
+	return q.DeleteFileCtx(context.Background(), objectKey)
+}
+
+func (q *QiniuUploader) DeleteFileCtx(ctx context.Context, objectKey string) error {
+	if err := q.doManage(ctx, http.MethodPost, q.rsHost+"/delete/"+q.entry(objectKey), nil, nil); err != nil {
+		return fmt.Errorf("删除七牛文件失败: %v", err)
+	}
+	return nil
+}
+
+// ListFiles 获取存储桶目录下的所有文件列表，可以通过后缀进行过滤
+func (q *QiniuUploader) ListFiles(directory string, suffixFilters ...string) ([]string, error) {
+	return q.ListFilesCtx(context.Background(), directory, suffixFilters...)
+}
+
+func (q *QiniuUploader) ListFilesCtx(ctx context.Context, directory string, suffixFilters ...string) ([]string, error) {
+	var files []string
+	err := q.walk(ctx, directory, func(items []qiniuListItem) error {
+		for _, item := range items {
+			if hasSuffix(item.Key, suffixFilters) {
+				files = append(files, item.Key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列出七牛对象失败: %v", err)
+	}
+	return files, nil
+}
+
+// ListObjects 分页列出七牛对象，按对象键排序
+func (q *QiniuUploader) ListObjects(ctx context.Context, opts ListOptions) (*ObjectPage, error) {
+	marker, err := decodeToken(opts.Token)
+	if err != nil {
+		return nil, err
+	}
+	res, err := q.listObjects(ctx, opts.Prefix, marker, opts.Delimiter, opts.limit())
+	if err != nil {
+		return nil, fmt.Errorf("列出七牛对象失败: %v", err)
+	}
+
+	page := &ObjectPage{List: make([]ObjectInfo, 0, len(res.Items)+len(res.CommonPrefixes))}
+	for _, item := range res.Items {
+		page.List = append(page.List, ObjectInfo{
+			Key:          item.Key,
+			Size:         item.Fsize,
+			ContentType:  item.MimeType,
+			ETag:         item.Hash,
+			LastModified: time.Unix(0, item.PutTime*100), // 单位为 100 纳秒
+		})
+	}
+	for _, prefix := range res.CommonPrefixes {
+		page.List = append(page.List, ObjectInfo{Key: prefix, IsDir: true})
+	}
+	sortObjects(page.List)
+	page.NextToken = encodeToken(res.Marker)
+	return page, nil
+}
+
+// PresignPut 七牛不支持预签名的 PUT 上传，客户端直传需要使用上传凭证
+func (q *QiniuUploader) PresignPut(objectKey string, opts PresignOptions) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+// PresignGet 生成私有空间的下载地址，需要配置存储桶绑定的域名
+func (q *QiniuUploader) PresignGet(objectKey string, opts PresignOptions) (string, error) {
+	if q.domain == "" {
+		return "", errors.New("failed to presign url: qiniu bucket url is required")
+	}
+	return q.privateURL(joinURL(qiniuHost(q.domain, ""), objectKey), opts.expires()), nil
+}
+
+// URL 生成七牛对象的访问地址，私有空间的签名包含域名，使用 CDN 域名时在替换后签名
+func (q *QiniuUploader) URL(objectKey string, opts ...URLOption) (string, error) {
+	o := q.options(opts)
+	if o.process != "" {
+		return "", ErrProcessUnsupported
+	}
+	if q.domain == "" && o.domain == "" {
+		return "", errors.New("upload: qiniu bucket url is not configured")
+	}
+
+	rawURL, err := withDomain(joinURL(qiniuHost(q.domain, ""), objectKey), o.domain)
+	if err != nil {
+		return "", err
+	}
+	if o.signed {
+		rawURL = q.privateURL(rawURL, o.expires)
+	}
+	return rawURL, nil
+}
+
+// privateURL 生成私有空间的下载地址
+func (q *QiniuUploader) privateURL(rawURL string, expires time.Duration) string {
+	rawURL += "?e=" + strconv.FormatInt(q.now().Add(expires).Unix(), 10)
+	return rawURL + "&token=" + q.accessKey + ":" + q.sign([]byte(rawURL))
+}
+
+// Open 通过下载域名读取对象，r 不为 nil 时只读取指定范围
+func (q *QiniuUploader) Open(objectKey string, r *Range) (io.ReadCloser, error) {
+	return q.OpenCtx(context.Background(), objectKey, r)
+}
+
+func (q *QiniuUploader) OpenCtx(ctx context.Context, objectKey string, r *Range) (io.ReadCloser, error) {
+	rawURL, err := q.PresignGet(objectKey, PresignOptions{})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		req.Header.Set("Range", r.header())
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("读取七牛对象失败: %v", err)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		resp.Body.Close()
+		return nil, qiniuObjectError(objectKey, "读取", &qiniuError{StatusCode: resp.StatusCode, Message: resp.Status})
+	}
+	return resp.Body, nil
+}
+
+// Download 将七牛对象保存到本地文件
+func (q *QiniuUploader) Download(objectKey, filePath string, r *Range) error {
+	return q.DownloadCtx(context.Background(), objectKey, filePath, r)
+}
+
+func (q *QiniuUploader) DownloadCtx(ctx context.Context, objectKey, filePath string, r *Range) error {
+	body, err := q.OpenCtx(ctx, objectKey, r)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return saveFile(ctx, filePath, body)
+}
+
+// Stat 获取七牛对象元数据
+func (q *QiniuUploader) Stat(objectKey string) (*ObjectInfo, error) {
+	return q.StatCtx(context.Background(), objectKey)
+}
+
+func (q *QiniuUploader) StatCtx(ctx context.Context, objectKey string) (*ObjectInfo, error) {
+	var res struct {
+		Fsize    int64  `json:"fsize"`
+		Hash     string `json:"hash"`
+		MimeType string `json:"mimeType"`
+		PutTime  int64  `json:"putTime"` // 单位为 100 纳秒
+	}
+	if err := q.doManage(ctx, http.MethodPost, q.rsHost+"/stat/"+q.entry(objectKey), nil, &res); err != nil {
+		return nil, qiniuObjectError(objectKey, "获取", err)
+	}
+
+	return &ObjectInfo{
+		Key:          objectKey,
+		Size:         res.Fsize,
+		ContentType:  res.MimeType,
+		ETag:         res.Hash,
+		LastModified: time.Unix(0, res.PutTime*100),
+	}, nil
+}
+
+// Exists 判断七牛对象是否存在
+func (q *QiniuUploader) Exists(objectKey string) (bool, error) {
+	return q.ExistsCtx(context.Background(), objectKey)
+}
+
+func (q *QiniuUploader) ExistsCtx(ctx context.Context, objectKey string) (bool, error) {
+	_, err := q.StatCtx(ctx, objectKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// qiniuObjectError 对象不存在时转换为 ErrObjectNotFound
+func qiniuObjectError(objectKey, action string, err error) error {
+	if isQiniuNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+	}
+	return fmt.Errorf("%s七牛对象失败: %v", action, err)
+}
+
+// upToken 生成上传凭证，scope 为 bucket:key 时仅允许上传该对象
+func (q *QiniuUploader) upToken(key string) string {
+	policy, _ := json.Marshal(map[string]any{
+		"scope":    q.bucket + ":" + key,
+		"deadline": q.now().Add(qiniuTokenExpiration).Unix(),
+	})
+	encoded := base64.URLEncoding.EncodeToString(policy)
+	return q.accessKey + ":" + q.sign([]byte(encoded)) + ":" + encoded
+}
+
+// qboxToken 生成管理凭证，表单请求的请求体参与签名
+func (q *QiniuUploader) qboxToken(u *url.URL, contentType string, body []byte) string {
+	data := u.EscapedPath()
+	if u.RawQuery != "" {
+		data += "?" + u.RawQuery
+	}
+	data += "\n"
+	if contentType == "application/x-www-form-urlencoded" {
+		data += string(body)
+	}
+	return "QBox " + q.accessKey + ":" + q.sign([]byte(data))
+}
+
+func (q *QiniuUploader) sign(data []byte) string {
+	h := hmac.New(sha1.New, []byte(q.secretKey))
+	h.Write(data)
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// entry 返回 bucket:key 的 URL 安全 Base64 编码
+func (q *QiniuUploader) entry(key string) string {
+	return base64.URLEncoding.EncodeToString([]byte(q.bucket + ":" + key))
+}
+
+// uploadsURL 返回分片上传 v2 接口地址
+func (q *QiniuUploader) uploadsURL(key string, elems ...string) string {
+	u := q.upHost + "/buckets/" + q.bucket + "/objects/" + base64.URLEncoding.EncodeToString([]byte(key)) + "/uploads"
+	for _, elem := range elems {
+		u += "/" + url.PathEscape(elem)
+	}
+	return u
+}
+
+// do 发送请求并解析 JSON 响应，非 2xx 响应转换为 qiniuError
+func (q *QiniuUploader) do(req *http.Request, out any) error {
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var er struct {
+			Error string `json:"error"`
+		}
+		if err = json.Unmarshal(data, &er); err != nil || er.Error == "" {
+			er.Error = strings.TrimSpace(string(data))
+		}
+		return &qiniuError{StatusCode: resp.StatusCode, Message: er.Error}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// doManage 发送使用管理凭证的请求
+func (q *QiniuUploader) doManage(ctx context.Context, method, rawURL string, form url.Values, out any) error {
+	var body []byte
+	if form != nil {
+		body = []byte(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", q.qboxToken(req.URL, req.Header.Get("Content-Type"), body))
+	return q.do(req, out)
+}
+
+// doUpload 发送使用上传凭证的请求
+func (q *QiniuUploader) doUpload(ctx context.Context, method, rawURL, key, contentType string, body io.Reader, size int64, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Authorization", "UpToken "+q.upToken(key))
+	return q.do(req, out)
+}
+
+func (q *QiniuUploader) initiateMultipartUpload(ctx context.Context, key string) (string, error) {
+	var res struct {
+		UploadId string `json:"uploadId"`
+	}
+	if err := q.doUpload(ctx, http.MethodPost, q.uploadsURL(key), key, "", nil, 0, &res); err != nil {
+		return "", err
+	}
+	return res.UploadId, nil
+}
+
+func (q *QiniuUploader) uploadPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	var res struct {
+		Etag string `json:"etag"`
+	}
+	rawURL := q.uploadsURL(key, uploadID, strconv.Itoa(partNumber))
+	if err := q.doUpload(ctx, http.MethodPut, rawURL, key, "application/octet-stream", reader, size, &res); err != nil {
+		return "", err
+	}
+	return res.Etag, nil
+}
+
+func (q *QiniuUploader) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) error {
+	req := struct {
+		Parts    []qiniuPart `json:"parts"`
+		MimeType string      `json:"mimeType,omitempty"`
+	}{
+		Parts:    make([]qiniuPart, 0, len(parts)),
+		MimeType: mime.TypeByExtension(path.Ext(key)),
+	}
+	for _, part := range parts {
+		req.Parts = append(req.Parts, qiniuPart{PartNumber: part.PartNumber, Etag: part.ETag})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	return q.doUpload(ctx, http.MethodPost, q.uploadsURL(key, uploadID), key, "application/json", bytes.NewReader(body), int64(len(body)), nil)
+}
+
+// listParts 分页列出已上传的分片
+func (q *QiniuUploader) listParts(ctx context.Context, key, uploadID string) ([]PartInfo, error) {
+	var parts []PartInfo
+	marker := 0
+	for {
+		rawURL := q.uploadsURL(key, uploadID)
+		if marker > 0 {
+			rawURL += "?part-number-marker=" + strconv.Itoa(marker)
+		}
+		var res struct {
+			PartNumberMarker int `json:"partNumberMarker"`
+			Parts            []struct {
+				PartNumber int    `json:"partNumber"`
+				Etag       string `json:"etag"`
+				Size       int64  `json:"size"`
+				PutTime    int64  `json:"putTime"` // 单位为秒
+			} `json:"parts"`
+		}
+		if err := q.doUpload(ctx, http.MethodGet, rawURL, key, "", nil, 0, &res); err != nil {
+			return nil, err
+		}
+		for _, part := range res.Parts {
+			parts = append(parts, PartInfo{
+				PartNumber:   part.PartNumber,
+				ETag:         part.Etag,
+				Size:         part.Size,
+				LastModified: time.Unix(part.PutTime, 0),
+			})
+		}
+		// partNumberMarker 为 0 表示已列举完毕
+		if res.PartNumberMarker <= marker {
+			return parts, nil
+		}
+		marker = res.PartNumberMarker
+	}
+}
+
+func (q *QiniuUploader) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return q.doUpload(ctx, http.MethodDelete, q.uploadsURL(key, uploadID), key, "", nil, 0, nil)
+}
+
+// putObject 使用表单上传单个对象
+func (q *QiniuUploader) putObject(ctx context.Context, key string, data []byte) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("token", q.upToken(key))
+	_ = w.WriteField("key", key)
+	file, err := w.CreateFormFile("file", path.Base(key))
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.upHost+"/", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return q.do(req, nil)
+}
+
+// uploadStream 上传长度未知的数据：不超过一个分片时使用表单上传，否则自动转为分片上传
+func (q *QiniuUploader) uploadStream(ctx context.Context, key string, reader io.Reader, partSize int64) error {
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, reader, partSize)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if n < partSize {
+		return q.putObject(ctx, key, buf.Bytes())
+	}
+
+	uploadID, err := q.initiateMultipartUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	var parts []Part
+	for partNumber := 1; n > 0; partNumber++ {
+		etag, err := q.uploadPart(ctx, key, uploadID, partNumber, bytes.NewReader(buf.Bytes()), n)
+		if err != nil {
+			_ = q.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+			return err
+		}
+		parts = append(parts, Part{ETag: etag, PartNumber: partNumber})
+
+		buf.Reset()
+		if n, err = io.CopyN(&buf, reader, partSize); err != nil && err != io.EOF {
+			_ = q.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+			return err
+		}
+	}
+
+	if err = q.completeMultipartUpload(ctx, key, uploadID, parts); err != nil {
+		_ = q.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+		return err
+	}
+	return nil
+}
+
+// listObjects 列举对象，marker 为上一页返回的位置标记
+func (q *QiniuUploader) listObjects(ctx context.Context, prefix, marker, delimiter string, limit int) (*qiniuListResult, error) {
+	query := url.Values{"bucket": {q.bucket}, "prefix": {prefix}}
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var res qiniuListResult
+	if err := q.doManage(ctx, http.MethodPost, q.rsfHost+"/list?"+query.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// walk 分页遍历前缀下的所有对象
+func (q *QiniuUploader) walk(ctx context.Context, prefix string, fn func(items []qiniuListItem) error) error {
+	marker := ""
+	for {
+		res, err := q.listObjects(ctx, prefix, marker, "", qiniuListLimit)
+		if err != nil {
+			return err
+		}
+		if err = fn(res.Items); err != nil {
+			return err
+		}
+		if res.Marker == "" {
+			return nil
+		}
+		marker = res.Marker
+	}
+}
+
+// batch 批量执行管理操作，超过单次上限时自动分批，任一操作失败即返回错误
+func (q *QiniuUploader) batch(ctx context.Context, ops []string) error {
+	for len(ops) > 0 {
+		n := min(len(ops), qiniuBatchSize)
+		var res []struct {
+			Code int `json:"code"`
+			Data struct {
+				Error string `json:"error"`
+			} `json:"data"`
+		}
+		if err := q.doManage(ctx, http.MethodPost, q.rsHost+"/batch", url.Values{"op": ops[:n]}, &res); err != nil {
+			return err
+		}
+		// 部分操作失败时接口返回 298，需要逐条检查结果
+		for i, r := range res {
+			if r.Code != http.StatusOK {
+				return fmt.Errorf("batch op %q failed: code=%d error=%s", ops[i], r.Code, r.Data.Error)
+			}
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+// fakeQiniuServer 是七牛上传、rs、rsf 接口的内存实现
+type fakeQiniuServer struct {
+	mu        sync.Mutex
+	bucket    string
+	accessKey string
+	secretKey string
+	limit     int // 单页返回的最大对象数，用于测试分页
+	objects   map[string][]byte
+	uploads   map[string]*fakeUpload
+	uploadID  int
+}
+
+func newFakeQiniuServer(bucket, accessKey, secretKey string) *fakeQiniuServer {
+	return &fakeQiniuServer{
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		limit:     2,
+		objects:   make(map[string][]byte),
+		uploads:   make(map[string]*fakeUpload),
+	}
+}
+
+func (f *fakeQiniuServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	elems := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodPost:
+		f.formUpload(w, r)
+	case r.Method == http.MethodGet && r.URL.Query().Has("token"):
+		f.download(w, r)
+	case elems[0] == "buckets" && len(elems) >= 5:
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "UpToken "+f.accessKey+":") {
+			f.error(w, http.StatusUnauthorized, "bad token")
+			return
+		}
+		key, _ := base64.URLEncoding.DecodeString(elems[3])
+		f.multipart(w, r, string(key), elems[5:])
+	default:
+		body, _ := io.ReadAll(r.Body)
+		if !f.checkQBox(r, body) {
+			f.error(w, http.StatusUnauthorized, "bad token")
+			return
+		}
+		f.manage(w, r, elems, body)
+	}
+}
+
+// checkQBox 校验管理凭证签名
+func (f *fakeQiniuServer) checkQBox(r *http.Request, body []byte) bool {
+	data := r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		data += "?" + r.URL.RawQuery
+	}
+	data += "\n"
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		data += string(body)
+	}
+	h := hmac.New(sha1.New, []byte(f.secretKey))
+	h.Write([]byte(data))
+	return r.Header.Get("Authorization") == "QBox "+f.accessKey+":"+base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (f *fakeQiniuServer) formUpload(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		f.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !strings.HasPrefix(r.FormValue("token"), f.accessKey+":") {
+		f.error(w, http.StatusUnauthorized, "bad token")
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		f.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, _ := io.ReadAll(file)
+	f.objects[r.FormValue("key")] = data
+	f.json(w, map[string]string{"key": r.FormValue("key"), "hash": etagOf(data)})
+}
+
+// download 模拟私有空间的下载域名
+func (f *fakeQiniuServer) download(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Query().Get("token"), f.accessKey+":") {
+		f.error(w, http.StatusUnauthorized, "bad token")
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	data, ok := f.objects[key]
+	if !ok {
+		f.error(w, http.StatusNotFound, "not found")
+		return
+	}
+	http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(data))
+}
+
+func (f *fakeQiniuServer) multipart(w http.ResponseWriter, r *http.Request, key string, elems []string) {
+	switch {
+	case len(elems) == 0 && r.Method == http.MethodPost:
+		f.uploadID++
+		id := strconv.Itoa(f.uploadID)
+		f.uploads[id] = &fakeUpload{key: key, parts: make(map[int][]byte)}
+		f.json(w, map[string]any{"uploadId": id})
+	case len(elems) == 2 && r.Method == http.MethodPut:
+		upload, ok := f.uploads[elems[0]]
+		if !ok {
+			f.error(w, 612, "no such uploadId")
+			return
+		}
+		n, _ := strconv.Atoi(elems[1])
+		data, _ := io.ReadAll(r.Body)
+		upload.parts[n] = data
+		f.json(w, map[string]string{"etag": etagOf(data)})
+	case len(elems) == 1 && r.Method == http.MethodPost:
+		upload, ok := f.uploads[elems[0]]
+		if !ok || upload.key != key {
+			f.error(w, 612, "no such uploadId")
+			return
+		}
+		var req struct {
+			Parts []qiniuPart `json:"parts"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var buf bytes.Buffer
+		for _, part := range req.Parts {
+			data, ok := upload.parts[part.PartNumber]
+			if !ok || etagOf(data) != part.Etag {
+				f.error(w, 400, "invalid part")
+				return
+			}
+			buf.Write(data)
+		}
+		f.objects[key] = buf.Bytes()
+		delete(f.uploads, elems[0])
+		f.json(w, map[string]string{"key": key})
+	case len(elems) == 1 && r.Method == http.MethodGet:
+		upload, ok := f.uploads[elems[0]]
+		if !ok || upload.key != key {
+			f.error(w, 612, "no such uploadId")
+			return
+		}
+		marker, _ := strconv.Atoi(r.URL.Query().Get("part-number-marker"))
+		numbers := make([]int, 0, len(upload.parts))
+		for n := range upload.parts {
+			if n > marker {
+				numbers = append(numbers, n)
+			}
+		}
+		sort.Ints(numbers)
+		res := map[string]any{"uploadId": elems[0], "partNumberMarker": 0}
+		var parts []map[string]any
+		for i, n := range numbers {
+			if i == f.limit {
+				res["partNumberMarker"] = numbers[i-1]
+				break
+			}
+			parts = append(parts, map[string]any{"partNumber": n, "etag": etagOf(upload.parts[n]), "size": len(upload.parts[n]), "putTime": time.Now().Unix()})
+		}
+		res["parts"] = parts
+		f.json(w, res)
+	case len(elems) == 1 && r.Method == http.MethodDelete:
+		delete(f.uploads, elems[0])
+	default:
+		f.error(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (f *fakeQiniuServer) manage(w http.ResponseWriter, r *http.Request, elems []string, body []byte) {
+	switch elems[0] {
+	case "list":
+		f.list(w, r.URL.Query())
+	case "stat":
+		key, _ := f.key(elems[len(elems)-1])
+		data, ok := f.objects[key]
+		if !ok {
+			f.error(w, 612, "no such file or directory")
+			return
+		}
+		f.json(w, map[string]any{"fsize": len(data), "hash": etagOf(data), "mimeType": "video/mp4", "putTime": 17000000000000000})
+	case "batch":
+		form, _ := url.ParseQuery(string(body))
+		results := make([]map[string]any, 0, len(form["op"]))
+		for _, op := range form["op"] {
+			code, msg := f.op(strings.Split(strings.TrimPrefix(op, "/"), "/"))
+			results = append(results, map[string]any{"code": code, "data": map[string]string{"error": msg}})
+		}
+		f.json(w, results)
+	default:
+		if code, msg := f.op(elems); code != http.StatusOK {
+			f.error(w, code, msg)
+		}
+	}
+}
+
+func (f *fakeQiniuServer) op(elems []string) (int, string) {
+	switch {
+	case elems[0] == "delete" && len(elems) == 2:
+		key, ok := f.key(elems[1])
+		if _, exists := f.objects[key]; !ok || !exists {
+			return 612, "no such file or directory"
+		}
+		delete(f.objects, key)
+	case elems[0] == "copy" && len(elems) >= 3:
+		src, ok1 := f.key(elems[1])
+		dest, ok2 := f.key(elems[2])
+		data, exists := f.objects[src]
+		if !ok1 || !ok2 || !exists {
+			return 612, "no such file or directory"
+		}
+		f.objects[dest] = data
+	default:
+		return 400, "bad op"
+	}
+	return http.StatusOK, ""
+}
+
+// key 从 bucket:key 的编码中解析对象键
+func (f *fakeQiniuServer) key(entry string) (string, bool) {
+	data, err := base64.URLEncoding.DecodeString(entry)
+	if err != nil {
+		return "", false
+	}
+	bucket, key, ok := strings.Cut(string(data), ":")
+	return key, ok && bucket == f.bucket
+}
+
+func (f *fakeQiniuServer) list(w http.ResponseWriter, query url.Values) {
+	if query.Get("bucket") != f.bucket {
+		f.error(w, 631, "no such bucket")
+		return
+	}
+	prefix, marker := query.Get("prefix"), query.Get("marker")
+
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var res qiniuListResult
+	for i, key := range keys {
+		if i == f.limit {
+			res.Marker = keys[i-1]
+			break
+		}
+		res.Items = append(res.Items, qiniuListItem{Key: key, Fsize: int64(len(f.objects[key])), Hash: etagOf(f.objects[key])})
+	}
+	f.json(w, res)
+}
+
+func (f *fakeQiniuServer) json(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeQiniuServer) error(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func newTestQiniuUploader(t *testing.T) (*QiniuUploader, *fakeQiniuServer) {
+	fake := newFakeQiniuServer("bucket", "ak", "sk")
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	var cfg config.StorageConf
+	cfg.Driver = "qiniu"
+	cfg.Qiniu.AccessKey = "ak"
+	cfg.Qiniu.SecretKey = "sk"
+	cfg.Qiniu.BucketName = "bucket"
+	cfg.Qiniu.BucketURL = srv.URL
+	cfg.Qiniu.UpHost = srv.URL
+	cfg.Qiniu.RsHost = srv.URL
+	cfg.Qiniu.RsfHost = srv.URL
+
+	u, err := NewUploader(cfg)
+	if err != nil {
+		t.Fatalf("NewUploader() error = %v", err)
+	}
+	return u.(*QiniuUploader), fake
+}
+
+func TestQiniuUploader(t *testing.T) {
+	u, fake := newTestQiniuUploader(t)
+	testUploader(t, u)
+
+	if got := string(fake.objects["course/c/1.mp4"]); got != "video-1" {
+		t.Errorf("copied object = %q", got)
+	}
+	if err := u.DeleteFile("missing.mp4"); err == nil {
+		t.Error("DeleteFile() of missing object should fail")
+	}
+}
+
+func TestQiniuUploaderLargeFile(t *testing.T) {
+	u, fake := newTestQiniuUploader(t)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), qiniuPartSize/16+1)
+	if _, err := u.UploadFile("large.bin", bytes.NewReader(data)); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if !bytes.Equal(fake.objects["large.bin"], data) {
+		t.Errorf("large object size = %d, want %d", len(fake.objects["large.bin"]), len(data))
+	}
+	if len(fake.uploads) != 0 {
+		t.Errorf("multipart upload left open: %v", fake.uploads)
+	}
+}
+
+func TestQiniuUploaderPresign(t *testing.T) {
+	u, _ := newTestQiniuUploader(t)
+	u.domain = "http://cdn.example.com"
+	u.now = func() time.Time { return time.Unix(1451491200, 0) }
+
+	got, err := u.PresignGet("course/a b.mp4", PresignOptions{Expires: time.Hour})
+	if err != nil {
+		t.Fatalf("PresignGet() error = %v", err)
+	}
+	base := "http://cdn.example.com/course/a%20b.mp4?e=1451494800"
+	if want := base + "&token=ak:" + u.sign([]byte(base)); got != want {
+		t.Errorf("PresignGet() = %q, want %q", got, want)
+	}
+
+	if _, err = u.PresignPut("course/a.mp4", PresignOptions{}); err != ErrPresignUnsupported {
+		t.Errorf("PresignPut() error = %v, want ErrPresignUnsupported", err)
+	}
+}
+
+func TestQiniuUploaderURL(t *testing.T) {
+	u, _ := newTestQiniuUploader(t)
+	u.domain = "http://bucket.example.com"
+	u.now = func() time.Time { return time.Unix(1451491200, 0) }
+
+	if got, err := u.URL("img/1.png"); err != nil || got != "http://bucket.example.com/img/1.png" {
+		t.Errorf("URL() = %q, %v", got, err)
+	}
+	got, _ := u.URL("img/1.png", WithDomain("cdn.example.com"), WithExpires(time.Hour))
+	base := "https://cdn.example.com/img/1.png?e=1451494800"
+	if want := base + "&token=ak:" + u.sign([]byte(base)); got != want {
+		t.Errorf("URL() signed = %q, want %q", got, want)
+	}
+}
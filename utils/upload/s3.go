@@ -0,0 +1,78 @@
+// upload/s3.go
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zhanghaidi/zero-common/config"
+
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Uploader 基于 S3 协议的存储，适用于 AWS S3、MinIO 等兼容服务
+type S3Uploader struct {
+	*objectStore
+}
+
+func init() {
+	Register("s3", func(cfg config.StorageConf) (Uploader, error) {
+		return NewS3Uploader(cfg)
+	})
+}
+
+func NewS3Uploader(cfg config.StorageConf) (*S3Uploader, error) {
+	c := cfg.S3
+	if c.BucketName == "" {
+		return nil, errors.New("failed to create S3 client: bucket name is required")
+	}
+	region := c.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	} else if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	baseURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	if c.UsePathStyle {
+		baseURL.Path = strings.TrimSuffix(baseURL.Path, "/") + "/" + c.BucketName
+	} else {
+		baseURL.Host = c.BucketName + "." + baseURL.Host
+	}
+
+	bucket := c.BucketName
+	client := &objectClient{
+		baseURL: baseURL,
+		client:  &http.Client{},
+		signer: &sigV4Signer{
+			accessKeyID:     c.AccessKeyID,
+			secretAccessKey: c.SecretAccessKey,
+			region:          region,
+			now:             time.Now,
+		},
+		headerPrefix: "x-amz-",
+		copySource: func(key string) string {
+			return "/" + bucket + "/" + uriEncode(key, false)
+		},
+	}
+
+	publicURL := c.BucketURL
+	if publicURL == "" {
+		publicURL = baseURL.String()
+	}
+	return &S3Uploader{objectStore: &objectStore{
+		urlResolver: urlResolver{cdnURL: cfg.CdnURL, private: cfg.Private},
+		client:      client,
+		name:        "S3",
+		publicURL:   publicURL,
+	}}, nil
+}
@@ -0,0 +1,587 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDeleteObjects 单次批量删除的最大对象数
+const maxDeleteObjects = 1000
+
+var (
+	// maxCopySize 单次复制请求的对象大小上限，S3 与 COS 均为 5GB
+	maxCopySize int64 = 5 * 1024 * 1024 * 1024
+	// copyPartSize 超过 maxCopySize 时分片复制使用的分片大小
+	copyPartSize int64 = 1024 * 1024 * 1024
+)
+
+// requestSigner 对发往对象存储的请求签名
+type requestSigner interface {
+	sign(req *http.Request)
+	presign(req *http.Request, expires time.Duration)
+}
+
+// objectError 对象存储返回的错误
+type objectError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *objectError) Error() string {
+	return fmt.Sprintf("object storage error: status=%d code=%s message=%s", e.StatusCode, e.Code, e.Message)
+}
+
+// objectClient 是 S3 XML 协议的最小实现，AWS S3、MinIO 与腾讯云 COS 共用，只是签名方式和头部前缀不同
+type objectClient struct {
+	baseURL      *url.URL // 存储桶根地址
+	client       *http.Client
+	signer       requestSigner
+	headerPrefix string                  // 厂商自定义头部前缀，如 x-amz-、x-cos-
+	copySource   func(key string) string // 生成复制请求的源对象标识
+}
+
+type objectSummary struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+type listBucketResult struct {
+	IsTruncated    bool
+	NextMarker     string
+	Contents       []objectSummary
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string
+}
+
+type completeMultipartUploadResult struct {
+	ETag string
+}
+
+type copyPartResult struct {
+	ETag string
+}
+
+type listPartsResult struct {
+	IsTruncated          bool
+	NextPartNumberMarker int
+	Parts                []struct {
+		PartNumber   int
+		ETag         string
+		Size         int64
+		LastModified time.Time
+	} `xml:"Part"`
+}
+
+type listMultipartUploadsResult struct {
+	IsTruncated        bool
+	NextKeyMarker      string
+	NextUploadIdMarker string
+	Uploads            []struct {
+		Key       string
+		UploadId  string
+		Initiated time.Time
+	} `xml:"Upload"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []completePart `xml:"Part"`
+}
+
+type completePart struct {
+	PartNumber int
+	ETag       string
+}
+
+type deleteRequest struct {
+	XMLName xml.Name       `xml:"Delete"`
+	Quiet   bool           `xml:"Quiet"`
+	Objects []deleteObject `xml:"Object"`
+}
+
+type deleteObject struct {
+	Key string
+}
+
+type deleteResult struct {
+	Errors []struct {
+		Key     string
+		Code    string
+		Message string
+	} `xml:"Error"`
+}
+
+type errorResponse struct {
+	XMLName xml.Name
+	Code    string
+	Message string
+}
+
+// objectURL 返回对象的完整地址，路径按 RFC 3986 编码以保证签名一致
+func (c *objectClient) objectURL(key string, query url.Values) *url.URL {
+	u := *c.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if key != "" || u.Path == "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do 发送签名请求，非 2xx 响应转换为 objectError
+func (c *objectClient) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	c.signer.sign(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		return nil, parseObjectError(resp.StatusCode, resp.Body)
+	}
+	return resp, nil
+}
+
+// doXML 发送请求并解析 XML 响应，部分接口在 200 响应中返回错误，需要一并识别
+func (c *objectClient) doXML(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	resp, err := c.do(ctx, method, key, query, header, reader, int64(len(body)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if isErrorDocument(data) {
+		return parseObjectError(resp.StatusCode, bytes.NewReader(data))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return xml.Unmarshal(data, out)
+}
+
+// isErrorDocument 判断 XML 文档的根节点是否为 Error
+func isErrorDocument(data []byte) bool {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local == "Error"
+		}
+	}
+}
+
+// isNotFound 判断错误是否为对象不存在
+func isNotFound(err error) bool {
+	var oe *objectError
+	return errors.As(err, &oe) && oe.StatusCode == http.StatusNotFound
+}
+
+func parseObjectError(status int, body io.Reader) error {
+	var er errorResponse
+	data, _ := io.ReadAll(body)
+	if err := xml.Unmarshal(data, &er); err != nil {
+		er.Message = strings.TrimSpace(string(data))
+	}
+	return &objectError{StatusCode: status, Code: er.Code, Message: er.Message}
+}
+
+// presignURL 生成预签名地址，header 中的请求头参与签名，客户端请求时必须携带相同的值
+func (c *objectClient) presignURL(method, key string, header http.Header, expires time.Duration) (string, error) {
+	req, err := http.NewRequest(method, c.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	c.signer.presign(req, expires)
+	return req.URL.String(), nil
+}
+
+// getObject 读取对象内容，rangeHeader 为空时读取整个对象
+func (c *objectClient) getObject(ctx context.Context, key, rangeHeader string) (io.ReadCloser, error) {
+	header := make(http.Header)
+	if rangeHeader != "" {
+		header.Set("Range", rangeHeader)
+	}
+	resp, err := c.do(ctx, http.MethodGet, key, nil, header, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// headObject 获取对象元数据
+func (c *objectClient) headObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, _, err := c.headObjectCRC64(ctx, key)
+	return info, err
+}
+
+// headObjectCRC64 获取对象元数据以及服务端计算的 CRC64，服务端不支持时 CRC64 为 0
+func (c *objectClient) headObjectCRC64(ctx context.Context, key string) (*ObjectInfo, uint64, error) {
+	resp, err := c.do(ctx, http.MethodHead, key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp.Body.Close()
+
+	lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	crc, _ := strconv.ParseUint(resp.Header.Get(c.headerPrefix+"hash-crc64ecma"), 10, 64)
+	return &ObjectInfo{
+		Key:          key,
+		Size:         resp.ContentLength,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: lastModified,
+	}, crc, nil
+}
+
+func (c *objectClient) putObject(ctx context.Context, key string, reader io.Reader, size int64, contentType string) (string, error) {
+	header := make(http.Header)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if reader == nil {
+		reader = http.NoBody
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, key, nil, header, reader, size)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+func (c *objectClient) initiateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	header := make(http.Header)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	var res initiateMultipartUploadResult
+	if err := c.doXML(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil, &res); err != nil {
+		return "", err
+	}
+	return res.UploadId, nil
+}
+
+// uploadPart 上传分片，contentMD5 不为空时由服务端校验内容
+func (c *objectClient) uploadPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader, size int64, contentMD5 string) (string, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(partNumber)},
+		"uploadId":   {uploadID},
+	}
+	header := make(http.Header)
+	if contentMD5 != "" {
+		header.Set("Content-MD5", contentMD5)
+	}
+	resp, err := c.do(ctx, http.MethodPut, key, query, header, reader, size)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// completeMultipartUpload 合并分片并返回对象的 ETag，服务端会校验分片的 ETag
+func (c *objectClient) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) (string, error) {
+	req := completeMultipartUpload{Parts: make([]completePart, 0, len(parts))}
+	for _, part := range parts {
+		req.Parts = append(req.Parts, completePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	header := http.Header{"Content-Type": {"application/xml"}}
+	var res completeMultipartUploadResult
+	if err = c.doXML(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, header, body, &res); err != nil {
+		return "", err
+	}
+	return res.ETag, nil
+}
+
+func (c *objectClient) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listParts 分页列出已上传的分片
+func (c *objectClient) listParts(ctx context.Context, key, uploadID string) ([]PartInfo, error) {
+	var parts []PartInfo
+	marker := 0
+	for {
+		query := url.Values{"uploadId": {uploadID}}
+		if marker > 0 {
+			query.Set("part-number-marker", strconv.Itoa(marker))
+		}
+		var res listPartsResult
+		if err := c.doXML(ctx, http.MethodGet, key, query, nil, nil, &res); err != nil {
+			return nil, err
+		}
+		for _, part := range res.Parts {
+			parts = append(parts, PartInfo{
+				PartNumber:   part.PartNumber,
+				ETag:         part.ETag,
+				Size:         part.Size,
+				LastModified: part.LastModified,
+			})
+		}
+		if !res.IsTruncated || res.NextPartNumberMarker <= marker {
+			return parts, nil
+		}
+		marker = res.NextPartNumberMarker
+	}
+}
+
+// listMultipartUploads 分页列出存储桶中未完成的分片上传
+func (c *objectClient) listMultipartUploads(ctx context.Context) ([]MultipartUpload, error) {
+	var uploads []MultipartUpload
+	query := url.Values{"uploads": {""}}
+	for {
+		var res listMultipartUploadsResult
+		if err := c.doXML(ctx, http.MethodGet, "", query, nil, nil, &res); err != nil {
+			return nil, err
+		}
+		for _, upload := range res.Uploads {
+			uploads = append(uploads, MultipartUpload{ObjectKey: upload.Key, UploadID: upload.UploadId, Initiated: upload.Initiated})
+		}
+		if !res.IsTruncated || res.NextKeyMarker == "" {
+			return uploads, nil
+		}
+		query = url.Values{
+			"uploads":          {""},
+			"key-marker":       {res.NextKeyMarker},
+			"upload-id-marker": {res.NextUploadIdMarker},
+		}
+	}
+}
+
+func (c *objectClient) copyObject(ctx context.Context, srcKey, destKey string) error {
+	header := make(http.Header)
+	header.Set(c.headerPrefix+"copy-source", c.copySource(srcKey))
+	return c.doXML(ctx, http.MethodPut, destKey, nil, header, nil, nil)
+}
+
+// copyFile 复制单个对象，不超过 maxCopySize 时直接复制，否则按 copyPartSize 分片复制
+func (c *objectClient) copyFile(ctx context.Context, srcKey, destKey string) error {
+	info, err := c.headObject(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	if info.Size <= maxCopySize {
+		return c.copyObject(ctx, srcKey, destKey)
+	}
+
+	uploadID, err := c.initiateMultipartUpload(ctx, destKey, info.ContentType)
+	if err != nil {
+		return err
+	}
+	var parts []Part
+	for offset, partNumber := int64(0), 1; offset < info.Size; offset, partNumber = offset+copyPartSize, partNumber+1 {
+		end := min(offset+copyPartSize, info.Size) - 1
+		etag, err := c.uploadPartCopy(ctx, srcKey, destKey, uploadID, partNumber, offset, end)
+		if err != nil {
+			_ = c.abortMultipartUpload(context.WithoutCancel(ctx), destKey, uploadID)
+			return err
+		}
+		parts = append(parts, Part{ETag: etag, PartNumber: partNumber})
+	}
+	if _, err = c.completeMultipartUpload(ctx, destKey, uploadID, parts); err != nil {
+		_ = c.abortMultipartUpload(context.WithoutCancel(ctx), destKey, uploadID)
+		return err
+	}
+	return nil
+}
+
+// uploadPartCopy 将源对象 [start, end] 范围内的内容复制为分片
+func (c *objectClient) uploadPartCopy(ctx context.Context, srcKey, destKey, uploadID string, partNumber int, start, end int64) (string, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(partNumber)},
+		"uploadId":   {uploadID},
+	}
+	header := make(http.Header)
+	header.Set(c.headerPrefix+"copy-source", c.copySource(srcKey))
+	header.Set(c.headerPrefix+"copy-source-range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	var res copyPartResult
+	if err := c.doXML(ctx, http.MethodPut, destKey, query, header, nil, &res); err != nil {
+		return "", err
+	}
+	return res.ETag, nil
+}
+
+func (c *objectClient) deleteObject(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// deleteObjects 批量删除对象，超过单次上限时自动分批
+func (c *objectClient) deleteObjects(ctx context.Context, keys []string) error {
+	for len(keys) > 0 {
+		n := min(len(keys), maxDeleteObjects)
+		req := deleteRequest{Quiet: true}
+		for _, key := range keys[:n] {
+			req.Objects = append(req.Objects, deleteObject{Key: key})
+		}
+		body, err := xml.Marshal(req)
+		if err != nil {
+			return err
+		}
+
+		sum := md5.Sum(body)
+		header := http.Header{
+			"Content-Type": {"application/xml"},
+			"Content-Md5":  {base64.StdEncoding.EncodeToString(sum[:])},
+		}
+		var res deleteResult
+		if err = c.doXML(ctx, http.MethodPost, "", url.Values{"delete": {""}}, header, body, &res); err != nil {
+			return err
+		}
+		if len(res.Errors) > 0 {
+			e := res.Errors[0]
+			return fmt.Errorf("failed to delete %q: %s %s", e.Key, e.Code, e.Message)
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// listObjects 列出对象，marker 为上一页最后一个键
+func (c *objectClient) listObjects(ctx context.Context, prefix, marker, delimiter string, maxKeys int) (*listBucketResult, error) {
+	query := url.Values{"prefix": {prefix}}
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+
+	var res listBucketResult
+	if err := c.doXML(ctx, http.MethodGet, "", query, nil, nil, &res); err != nil {
+		return nil, err
+	}
+	if res.IsTruncated && res.NextMarker == "" {
+		// 未指定 delimiter 时 S3 不返回 NextMarker，使用本页最后一个键
+		if n := len(res.Contents); n > 0 {
+			res.NextMarker = res.Contents[n-1].Key
+		}
+		if n := len(res.CommonPrefixes); n > 0 && res.CommonPrefixes[n-1].Prefix > res.NextMarker {
+			res.NextMarker = res.CommonPrefixes[n-1].Prefix
+		}
+	}
+	return &res, nil
+}
+
+// walkObjects 分页遍历前缀下的所有对象
+func (c *objectClient) walkObjects(ctx context.Context, prefix string, fn func(objects []objectSummary) error) error {
+	marker := ""
+	for {
+		res, err := c.listObjects(ctx, prefix, marker, "", 0)
+		if err != nil {
+			return err
+		}
+		if err = fn(res.Contents); err != nil {
+			return err
+		}
+		if !res.IsTruncated {
+			return nil
+		}
+		marker = res.NextMarker
+	}
+}
+
+// uploadStream 上传长度未知的数据：不超过一个分片时直接上传，否则自动转为分片上传
+func (c *objectClient) uploadStream(ctx context.Context, key string, reader io.Reader, partSize int64, contentType string) error {
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, reader, partSize)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if n < partSize {
+		_, err = c.putObject(ctx, key, bytes.NewReader(buf.Bytes()), n, contentType)
+		return err
+	}
+
+	uploadID, err := c.initiateMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		return err
+	}
+
+	var parts []Part
+	for partNumber := 1; n > 0; partNumber++ {
+		etag, err := c.uploadPart(ctx, key, uploadID, partNumber, bytes.NewReader(buf.Bytes()), n, "")
+		if err != nil {
+			_ = c.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+			return err
+		}
+		parts = append(parts, Part{ETag: etag, PartNumber: partNumber})
+
+		buf.Reset()
+		if n, err = io.CopyN(&buf, reader, partSize); err != nil && err != io.EOF {
+			_ = c.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+			return err
+		}
+	}
+
+	if _, err = c.completeMultipartUpload(ctx, key, uploadID, parts); err != nil {
+		_ = c.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+// ErrSessionNotFound 分片上传会话不存在或已结束
+var ErrSessionNotFound = errors.New("upload: multipart upload session not found")
+
+// uploadIDPattern 合法的 uploadID，会话 ID 会用于文件名，需要限制字符
+var uploadIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// PartInfo 已上传的分片信息
+type PartInfo struct {
+	PartNumber   int       `json:"partNumber"`
+	ETag         string    `json:"etag"`
+	Size         int64     `json:"size"`
+	Checksum     string    `json:"checksum,omitempty"` // 分片内容的 MD5，十六进制
+	CRC64        uint64    `json:"crc64,omitempty"`    // 分片内容的 CRC64 ECMA
+	LastModified time.Time `json:"lastModified"`
+}
+
+// MultipartUpload 未完成的分片上传
+type MultipartUpload struct {
+	ObjectKey string
+	UploadID  string
+	Initiated time.Time
+}
+
+// UploadSession 分片上传会话，记录断点续传需要的状态
+type UploadSession struct {
+	UploadID   string           `json:"uploadId"`
+	ObjectKey  string           `json:"objectKey"`
+	PartCount  int              `json:"partCount,omitempty"`  // 预期的分片数量，0 表示未知
+	ObjectSize int64            `json:"objectSize,omitempty"` // 预期的对象大小，0 表示未知
+	Parts      map[int]PartInfo `json:"parts"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	UpdatedAt  time.Time        `json:"updatedAt"`
+}
+
+// SortedParts 返回按分片序号排序的分片列表
+func (s *UploadSession) SortedParts() []PartInfo {
+	parts := make([]PartInfo, 0, len(s.Parts))
+	for _, part := range s.Parts {
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	return parts
+}
+
+// MultipartOption 初始化分片上传的可选参数
+type MultipartOption func(*UploadSession)
+
+// WithPartCount 记录预期的分片数量，完成上传时校验
+func WithPartCount(n int) MultipartOption {
+	return func(s *UploadSession) {
+		s.PartCount = n
+	}
+}
+
+// WithObjectSize 记录预期的对象大小，完成上传时校验
+func WithObjectSize(size int64) MultipartOption {
+	return func(s *UploadSession) {
+		s.ObjectSize = size
+	}
+}
+
+// WithObjectKey 指定合并后的对象键，不指定时由驱动生成随机键
+func WithObjectKey(key string) MultipartOption {
+	return func(s *UploadSession) {
+		s.ObjectKey = key
+	}
+}
+
+// multipartKey 返回 opts 通过 WithObjectKey 指定的对象键，未指定时根据 filename 的扩展名生成随机键，避免并发初始化时冲突
+func multipartKey(filename string, opts []MultipartOption) string {
+	var session UploadSession
+	for _, opt := range opts {
+		opt(&session)
+	}
+	if session.ObjectKey != "" {
+		return session.ObjectKey
+	}
+	return fmt.Sprintf("chunk-upload/%s%s", rand.Text(), strings.ToLower(path.Ext(filename)))
+}
+
+// SessionStore 持久化分片上传会话
+type SessionStore interface {
+	Create(ctx context.Context, session *UploadSession) error
+	// Get 获取会话，不存在时返回 ErrSessionNotFound
+	Get(ctx context.Context, uploadID string) (*UploadSession, error)
+	// PutPart 记录已上传的分片，会话不存在时返回 ErrSessionNotFound
+	PutPart(ctx context.Context, uploadID string, part PartInfo) error
+	Delete(ctx context.Context, uploadID string) error
+	List(ctx context.Context) ([]*UploadSession, error)
+}
+
+// FileSessionStore 将会话以 JSON 文件保存在本地目录，仅保证单进程内的并发安全
+type FileSessionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileSessionStore 创建基于本地文件的会话存储
+func NewFileSessionStore(dir string) *FileSessionStore {
+	return &FileSessionStore{dir: dir}
+}
+
+func (s *FileSessionStore) Create(ctx context.Context, session *UploadSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !uploadIDPattern.MatchString(session.UploadID) {
+		return fmt.Errorf("upload: invalid upload id %q", session.UploadID)
+	}
+	return s.write(session)
+}
+
+func (s *FileSessionStore) Get(ctx context.Context, uploadID string) (*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(uploadID)
+}
+
+func (s *FileSessionStore) PutPart(ctx context.Context, uploadID string, part PartInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.read(uploadID)
+	if err != nil {
+		return err
+	}
+	session.Parts[part.PartNumber] = part
+	session.UpdatedAt = time.Now()
+	return s.write(session)
+}
+
+func (s *FileSessionStore) Delete(ctx context.Context, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !uploadIDPattern.MatchString(uploadID) {
+		return nil
+	}
+	if err := os.Remove(s.path(uploadID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除上传会话失败: %w", err)
+	}
+	return nil
+}
+
+func (s *FileSessionStore) List(ctx context.Context) ([]*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取上传会话目录失败: %w", err)
+	}
+
+	var sessions []*UploadSession
+	for _, entry := range entries {
+		uploadID, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		session, err := s.read(uploadID)
+		if err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (s *FileSessionStore) path(uploadID string) string {
+	return filepath.Join(s.dir, uploadID+".json")
+}
+
+func (s *FileSessionStore) read(uploadID string) (*UploadSession, error) {
+	if !uploadIDPattern.MatchString(uploadID) {
+		return nil, ErrSessionNotFound
+	}
+	data, err := os.ReadFile(s.path(uploadID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("读取上传会话失败: %w", err)
+	}
+
+	var session UploadSession
+	if err = json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("解析上传会话失败: %w", err)
+	}
+	if session.Parts == nil {
+		session.Parts = make(map[int]PartInfo)
+	}
+	return &session, nil
+}
+
+// write 先写临时文件再重命名，避免读到不完整的会话
+func (s *FileSessionStore) write(session *UploadSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("创建上传会话目录失败: %w", err)
+	}
+
+	file, err := createAtomicFile(s.path(session.UploadID), 0644)
+	if err != nil {
+		return fmt.Errorf("写入上传会话失败: %w", err)
+	}
+	defer file.Close()
+
+	if _, err = file.Write(data); err != nil {
+		return fmt.Errorf("写入上传会话失败: %w", err)
+	}
+	if err = file.Commit(); err != nil {
+		return fmt.Errorf("写入上传会话失败: %w", err)
+	}
+	return nil
+}
+
+const (
+	sessionMetaField = "meta"
+	sessionPartField = "part:"
+)
+
+// putPartScript 会话存在时写入分片并续期
+var putPartScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// RedisSessionStore 将会话保存在 Redis 哈希中，每个分片单独一个字段，支持多实例并发上传
+type RedisSessionStore struct {
+	Redis      redis.UniversalClient
+	PreKey     string
+	Expiration time.Duration // 会话在最后一次写入后的保留时长
+}
+
+// NewRedisSessionStore returns a session store using redis
+func NewRedisSessionStore(r redis.UniversalClient, expiration time.Duration) *RedisSessionStore {
+	return &RedisSessionStore{
+		Redis:      r,
+		PreKey:     config.RedisUploadPrefix,
+		Expiration: expiration,
+	}
+}
+
+func (s *RedisSessionStore) Create(ctx context.Context, session *UploadSession) error {
+	meta := *session
+	meta.Parts = nil
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	key := s.PreKey + session.UploadID
+	_, err = s.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, sessionMetaField, data)
+		for _, part := range session.Parts {
+			partData, _ := json.Marshal(part)
+			pipe.HSet(ctx, key, sessionPartField+strconv.Itoa(part.PartNumber), partData)
+		}
+		pipe.PExpire(ctx, key, s.Expiration)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisSessionStore) Get(ctx context.Context, uploadID string) (*UploadSession, error) {
+	fields, err := s.Redis.HGetAll(ctx, s.PreKey+uploadID).Result()
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := fields[sessionMetaField]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	var session UploadSession
+	if err = json.Unmarshal([]byte(meta), &session); err != nil {
+		return nil, fmt.Errorf("解析上传会话失败: %w", err)
+	}
+	session.Parts = make(map[int]PartInfo)
+	for field, value := range fields {
+		if !strings.HasPrefix(field, sessionPartField) {
+			continue
+		}
+		var part PartInfo
+		if err = json.Unmarshal([]byte(value), &part); err != nil {
+			return nil, fmt.Errorf("解析上传分片失败: %w", err)
+		}
+		session.Parts[part.PartNumber] = part
+		if part.LastModified.After(session.UpdatedAt) {
+			session.UpdatedAt = part.LastModified
+		}
+	}
+	return &session, nil
+}
+
+func (s *RedisSessionStore) PutPart(ctx context.Context, uploadID string, part PartInfo) error {
+	data, err := json.Marshal(part)
+	if err != nil {
+		return err
+	}
+
+	ok, err := putPartScript.Run(ctx, s.Redis, []string{s.PreKey + uploadID},
+		sessionPartField+strconv.Itoa(part.PartNumber), data, s.Expiration.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *RedisSessionStore) Delete(ctx context.Context, uploadID string) error {
+	return s.Redis.Del(ctx, s.PreKey+uploadID).Err()
+}
+
+func (s *RedisSessionStore) List(ctx context.Context) ([]*UploadSession, error) {
+	var sessions []*UploadSession
+	iter := s.Redis.Scan(ctx, 0, s.PreKey+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		session, err := s.Get(ctx, strings.TrimPrefix(iter.Val(), s.PreKey))
+		if err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, iter.Err()
+}
@@ -0,0 +1,128 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"hash/crc64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSessionStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	stores := map[string]SessionStore{
+		"file":  NewFileSessionStore(t.TempDir()),
+		"redis": NewRedisSessionStore(rds, time.Hour),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now().Truncate(time.Second)
+
+			if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Get(missing) error = %v", err)
+			}
+			if err := store.PutPart(ctx, "missing", PartInfo{PartNumber: 1}); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("PutPart(missing) error = %v", err)
+			}
+
+			session := &UploadSession{UploadID: "u1", ObjectKey: "chunk-upload/u1.mp4", PartCount: 2, CreatedAt: now, UpdatedAt: now}
+			if err := store.Create(ctx, session); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			parts := []PartInfo{
+				{PartNumber: 2, ETag: "e2", Size: 3, Checksum: "c2", LastModified: now},
+				{PartNumber: 1, ETag: "e1", Size: 5, Checksum: "c1", LastModified: now},
+			}
+			for _, part := range parts {
+				if err := store.PutPart(ctx, "u1", part); err != nil {
+					t.Fatalf("PutPart(%d) error = %v", part.PartNumber, err)
+				}
+			}
+
+			got, err := store.Get(ctx, "u1")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got.ObjectKey != session.ObjectKey || got.PartCount != 2 || !got.CreatedAt.Equal(now) {
+				t.Errorf("Get() = %+v", got)
+			}
+			sorted := got.SortedParts()
+			for i := range sorted {
+				sorted[i].LastModified = now
+			}
+			if want := []PartInfo{parts[1], parts[0]}; !reflect.DeepEqual(sorted, want) {
+				t.Errorf("SortedParts() = %+v, want %+v", sorted, want)
+			}
+
+			list, err := store.List(ctx)
+			if err != nil || len(list) != 1 || list[0].UploadID != "u1" {
+				t.Errorf("List() = %v, %v", list, err)
+			}
+
+			if err = store.Delete(ctx, "u1"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err = store.Get(ctx, "u1"); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Get() after Delete error = %v", err)
+			}
+		})
+	}
+
+	if _, err := stores["file"].Get(context.Background(), "../u1"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get() with invalid id error = %v", err)
+	}
+}
+
+func TestLocalUploaderMultipart(t *testing.T) {
+	u := NewLocalUploader(t.TempDir())
+	testMultipartResume(t, u)
+	testPartChecksum(t, u)
+
+	first, _, _ := u.InitiateMultipartUpload("a.mp4")
+	second, _, _ := u.InitiateMultipartUpload("a.mp4")
+	if first == second {
+		t.Errorf("InitiateMultipartUpload() returned duplicated upload id %q", first)
+	}
+
+	uploadID, objectKey, err := u.InitiateMultipartUpload("a.mp4", WithPartCount(2), WithObjectSize(6))
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	if _, err = u.UploadPart(objectKey, "unknown", 1, strings.NewReader("abc"), 3); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("UploadPart() with unknown upload id error = %v", err)
+	}
+	if _, err = u.UploadPart("chunk-upload/other.mp4", uploadID, 1, strings.NewReader("abc"), 3); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("UploadPart() with other object key error = %v", err)
+	}
+	etag, err := u.UploadPart(objectKey, uploadID, 1, strings.NewReader("abc"), 3)
+	if err != nil || etag != `"900150983cd24fb0d6963f7d28e17f72"` {
+		t.Fatalf("UploadPart() = %s, %v", etag, err)
+	}
+	if _, err = u.CompleteMultipartUpload(objectKey, uploadID, []Part{{ETag: etag, PartNumber: 1}}); err == nil {
+		t.Error("CompleteMultipartUpload() with missing parts should fail")
+	}
+
+	// 会话持久化后，新的实例可以继续上传
+	resumed := NewLocalUploader(u.directory)
+	parts, err := resumed.ListParts(objectKey, uploadID)
+	if err != nil || len(parts) != 1 || parts[0].Checksum != "900150983cd24fb0d6963f7d28e17f72" {
+		t.Fatalf("ListParts() = %+v, %v", parts, err)
+	}
+	etag2, err := resumed.UploadPart(objectKey, uploadID, 2, strings.NewReader("def"), 3)
+	if err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+	res, err := resumed.CompleteMultipartUpload(objectKey, uploadID, []Part{{ETag: etag, PartNumber: 1}, {ETag: etag2, PartNumber: 2}})
+	if err != nil || res.MD5 != "e80b5017098950fc58aad83c8c14978e" || res.CRC64 != crc64.Checksum([]byte("abcdef"), crc64Table) {
+		t.Fatalf("CompleteMultipartUpload() = %+v, %v", res, err)
+	}
+	testRead(t, resumed, objectKey, "abcdef")
+}
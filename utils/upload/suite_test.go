@@ -0,0 +1,310 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash/crc64"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testUploader 验证驱动在 Uploader 接口层面的通用行为
+func testUploader(t *testing.T, u Uploader) {
+	t.Helper()
+
+	mustUpload := func(key, content string) {
+		t.Helper()
+		got, err := u.UploadFile(key, strings.NewReader(content))
+		if err != nil || got != key {
+			t.Fatalf("UploadFile(%q) = %q, %v", key, got, err)
+		}
+	}
+	mustList := func(dir string, suffix ...string) []string {
+		t.Helper()
+		files, err := u.ListFiles(dir, suffix...)
+		if err != nil {
+			t.Fatalf("ListFiles(%q) error = %v", dir, err)
+		}
+		sort.Strings(files)
+		return files
+	}
+
+	mustUpload("course/a/1.mp4", "video-1")
+	mustUpload("course/a/2.jpg", "image-2")
+	mustUpload("course/a/keep/3.mp4", "video-3")
+	mustUpload("course/b/4.mp4", "video-4")
+
+	if got, want := mustList("course/a/", ".mp4"), []string{"course/a/1.mp4", "course/a/keep/3.mp4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListFiles() = %v, want %v", got, want)
+	}
+
+	testRead(t, u, "course/a/1.mp4", "video-1")
+
+	uploadID, objectKey, err := u.InitiateMultipartUpload("movie.MP4")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	if !strings.HasPrefix(objectKey, "chunk-upload/") || !strings.HasSuffix(objectKey, ".mp4") {
+		t.Errorf("InitiateMultipartUpload() objectKey = %q", objectKey)
+	}
+	var parts []Part
+	for i, chunk := range []string{"part-1|", "part-2|", "part-3"} {
+		etag, err := u.UploadPart(objectKey, uploadID, i+1, bytes.NewReader([]byte(chunk)), int64(len(chunk)))
+		if err != nil {
+			t.Fatalf("UploadPart(%d) error = %v", i+1, err)
+		}
+		parts = append(parts, Part{ETag: etag, PartNumber: i + 1})
+	}
+	if got, err := u.CompleteMultipartUpload(objectKey, uploadID, parts); err != nil || got.ObjectKey != objectKey || got.Size != 20 || got.ETag == "" {
+		t.Fatalf("CompleteMultipartUpload() = %+v, %v", got, err)
+	}
+	if got := mustList("chunk-upload/"); !reflect.DeepEqual(got, []string{objectKey}) {
+		t.Errorf("ListFiles(chunk-upload/) = %v", got)
+	}
+
+	if err = u.CopyFolder("course/a/", "course/c/"); err != nil {
+		t.Fatalf("CopyFolder() error = %v", err)
+	}
+	if got, want := mustList("course/c/"), []string{"course/c/1.mp4", "course/c/2.jpg", "course/c/keep/3.mp4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListFiles() after CopyFolder = %v, want %v", got, want)
+	}
+
+	if err = u.CopyFile("course/b/4.mp4", "course/d/4.mp4"); err != nil {
+		t.Fatalf("CopyFile() error = %v", err)
+	}
+	testRead(t, u, "course/d/4.mp4", "video-4")
+	if err = u.CopyFile("course/missing.mp4", "course/d/5.mp4"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("CopyFile(missing) error = %v, want ErrObjectNotFound", err)
+	}
+
+	if err = u.DeleteFolder("course/a/", "keep"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	if got, want := mustList("course/a/"), []string{"course/a/keep/3.mp4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListFiles() after DeleteFolder = %v, want %v", got, want)
+	}
+
+	testMultipartResume(t, u)
+	testPartChecksum(t, u)
+
+	if err = u.DeleteFile("course/b/4.mp4"); err != nil {
+		t.Fatalf("DeleteFile() error = %v", err)
+	}
+	if got := mustList("course/b/"); len(got) != 0 {
+		t.Errorf("ListFiles() after DeleteFile = %v", got)
+	}
+}
+
+// testRead 验证对象读取、范围读取、下载与元数据接口，key 的内容必须为 content
+func testRead(t *testing.T, u Uploader, key, content string) {
+	t.Helper()
+
+	read := func(r *Range) string {
+		t.Helper()
+		body, err := u.Open(key, r)
+		if err != nil {
+			t.Fatalf("Open(%q, %+v) error = %v", key, r, err)
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("read %q error = %v", key, err)
+		}
+		return string(data)
+	}
+	if got := read(nil); got != content {
+		t.Errorf("Open() = %q, want %q", got, content)
+	}
+	if got := read(&Range{Offset: 2, Length: 3}); got != content[2:5] {
+		t.Errorf("Open(2, 3) = %q, want %q", got, content[2:5])
+	}
+	if got := read(&Range{Offset: 2}); got != content[2:] {
+		t.Errorf("Open(2) = %q, want %q", got, content[2:])
+	}
+
+	filePath := filepath.Join(t.TempDir(), "sub", "download")
+	if err := u.Download(key, filePath, &Range{Offset: 1, Length: 2}); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != content[1:3] {
+		t.Errorf("Download() = %q, want %q", data, content[1:3])
+	}
+
+	info, err := u.Stat(key)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Key != key || info.Size != int64(len(content)) || info.ETag == "" || info.LastModified.IsZero() {
+		t.Errorf("Stat() = %+v", info)
+	}
+	if ok, err := u.Exists(key); !ok || err != nil {
+		t.Errorf("Exists(%q) = %v, %v", key, ok, err)
+	}
+
+	missing := key + ".missing"
+	if _, err = u.Stat(missing); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Stat(missing) error = %v, want ErrObjectNotFound", err)
+	}
+	if _, err = u.Open(missing, nil); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Open(missing) error = %v, want ErrObjectNotFound", err)
+	}
+	if ok, err := u.Exists(missing); ok || err != nil {
+		t.Errorf("Exists(missing) = %v, %v", ok, err)
+	}
+}
+
+// testMultipartResume 验证断点续传所需的 ListParts 与 AbortMultipartUpload
+func testMultipartResume(t *testing.T, u Uploader) {
+	t.Helper()
+
+	uploadID, objectKey, err := u.InitiateMultipartUpload("resume.bin", WithPartCount(3))
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	chunks := map[int]string{3: "c", 1: "aa", 2: "bbb"}
+	etags := make(map[int]string)
+	for _, n := range []int{3, 1, 2} {
+		etag, err := u.UploadPart(objectKey, uploadID, n, strings.NewReader(chunks[n]), int64(len(chunks[n])))
+		if err != nil {
+			t.Fatalf("UploadPart(%d) error = %v", n, err)
+		}
+		etags[n] = etag
+	}
+
+	parts, err := u.ListParts(objectKey, uploadID)
+	if err != nil {
+		t.Fatalf("ListParts() error = %v", err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("ListParts() = %+v", parts)
+	}
+	for i, part := range parts {
+		if part.PartNumber != i+1 || part.Size != int64(len(chunks[i+1])) || part.ETag != etags[i+1] {
+			t.Errorf("ListParts()[%d] = %+v", i, part)
+		}
+	}
+
+	var complete []Part
+	for _, part := range parts {
+		complete = append(complete, Part{ETag: part.ETag, PartNumber: part.PartNumber})
+	}
+	if _, err = u.CompleteMultipartUpload(objectKey, uploadID, complete); err != nil {
+		t.Fatalf("CompleteMultipartUpload() error = %v", err)
+	}
+	if _, err = u.ListParts(objectKey, uploadID); err == nil {
+		t.Error("ListParts() after complete should fail")
+	}
+	if err = u.DeleteFile(objectKey); err != nil {
+		t.Fatalf("DeleteFile() error = %v", err)
+	}
+
+	uploadID, objectKey, err = u.InitiateMultipartUpload("abort.bin")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	if _, err = u.UploadPart(objectKey, uploadID, 1, strings.NewReader("x"), 1); err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+	if err = u.AbortMultipartUpload(objectKey, uploadID); err != nil {
+		t.Fatalf("AbortMultipartUpload() error = %v", err)
+	}
+	if _, err = u.ListParts(objectKey, uploadID); err == nil {
+		t.Error("ListParts() after abort should fail")
+	}
+
+	// 连续初始化的上传使用不同的对象键
+	id1, key1, err1 := u.InitiateMultipartUpload("same.bin")
+	id2, key2, err2 := u.InitiateMultipartUpload("same.bin")
+	if err1 != nil || err2 != nil || key1 == key2 {
+		t.Errorf("InitiateMultipartUpload() keys = %q, %q, errors = %v, %v", key1, key2, err1, err2)
+	}
+	_ = u.AbortMultipartUpload(key1, id1)
+	_ = u.AbortMultipartUpload(key2, id2)
+
+	// WithObjectKey 指定合并后的对象键
+	uploadID, objectKey, err = u.InitiateMultipartUpload("any.bin", WithObjectKey("media/chosen.bin"))
+	if err != nil || objectKey != "media/chosen.bin" {
+		t.Fatalf("InitiateMultipartUpload() with key = %q, %v", objectKey, err)
+	}
+	etag, err := u.UploadPart(objectKey, uploadID, 1, strings.NewReader("chosen"), 6)
+	if err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+	if _, err = u.CompleteMultipartUpload(objectKey, uploadID, []Part{{ETag: etag, PartNumber: 1}}); err != nil {
+		t.Fatalf("CompleteMultipartUpload() error = %v", err)
+	}
+	testRead(t, u, "media/chosen.bin", "chosen")
+	_ = u.DeleteFile(objectKey)
+}
+
+// testPartChecksum 验证分片校验值与完成上传时的 ETag 校验
+func testPartChecksum(t *testing.T, u Uploader) {
+	t.Helper()
+
+	uploadID, objectKey, err := u.InitiateMultipartUpload("checksum.bin")
+	if err != nil {
+		t.Fatalf("InitiateMultipartUpload() error = %v", err)
+	}
+	defer func() { _ = u.DeleteFile(objectKey) }()
+
+	// 校验失败的分片不能残留，上传后才能校验的驱动会取消整个上传，需要重新初始化
+	checkRejected := func(err error) {
+		t.Helper()
+		if !errors.Is(err, ErrSessionNotFound) {
+			if parts, _ := u.ListParts(objectKey, uploadID); len(parts) != 0 {
+				t.Errorf("ListParts() after checksum mismatch = %+v", parts)
+			}
+			return
+		}
+		if _, err = u.ListParts(objectKey, uploadID); err == nil {
+			t.Error("ListParts() after aborted upload should fail")
+		}
+		if uploadID, objectKey, err = u.InitiateMultipartUpload("checksum.bin"); err != nil {
+			t.Fatalf("InitiateMultipartUpload() error = %v", err)
+		}
+	}
+
+	content := "checksum"
+	sum := md5.Sum([]byte(content))
+	contentMD5 := base64.StdEncoding.EncodeToString(sum[:])
+	crc := crc64.Checksum([]byte(content), crc64Table)
+
+	badMD5 := base64.StdEncoding.EncodeToString(make([]byte, md5.Size))
+	if _, err = u.UploadPart(objectKey, uploadID, 1, strings.NewReader(content), int64(len(content)), WithContentMD5(badMD5)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("UploadPart() with bad md5 error = %v, want ErrChecksumMismatch", err)
+	}
+	checkRejected(err)
+	if _, err = u.UploadPart(objectKey, uploadID, 1, strings.NewReader(content), int64(len(content)), WithCRC64(crc+1)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("UploadPart() with bad crc64 error = %v, want ErrChecksumMismatch", err)
+	}
+	checkRejected(err)
+	etag, err := u.UploadPart(objectKey, uploadID, 1, strings.NewReader(content), int64(len(content)), WithContentMD5(contentMD5), WithCRC64(crc))
+	if err != nil {
+		t.Fatalf("UploadPart() error = %v", err)
+	}
+
+	if _, err = u.CompleteMultipartUpload(objectKey, uploadID, []Part{{ETag: `"bad"`, PartNumber: 1}}); err == nil {
+		t.Error("CompleteMultipartUpload() with bad etag should fail")
+	}
+	res, err := u.CompleteMultipartUpload(objectKey, uploadID, []Part{{ETag: etag, PartNumber: 1}})
+	if err != nil {
+		t.Fatalf("CompleteMultipartUpload() error = %v", err)
+	}
+	if res.ObjectKey != objectKey || res.Size != int64(len(content)) {
+		t.Errorf("CompleteMultipartUpload() = %+v", res)
+	}
+	if res.MD5 != "" && res.MD5 != hex.EncodeToString(sum[:]) {
+		t.Errorf("CompleteMultipartUpload() md5 = %s", res.MD5)
+	}
+	if res.CRC64 != 0 && res.CRC64 != crc {
+		t.Errorf("CompleteMultipartUpload() crc64 = %d, want %d", res.CRC64, crc)
+	}
+}
@@ -2,39 +2,317 @@
 package upload
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/zhanghaidi/zero-common/config"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"io"
 )
 
+// DefaultPresignExpires 预签名地址的默认有效期
+const DefaultPresignExpires = 15 * time.Minute
+
+var (
+	// ErrPresignUnsupported 驱动不支持生成该类预签名地址
+	ErrPresignUnsupported = errors.New("upload: presign is not supported by this driver")
+	// ErrPresignMaxSize 驱动生成的预签名地址无法限制上传大小
+	ErrPresignMaxSize = errors.New("upload: presigned url of this driver cannot limit upload size")
+	// ErrObjectNotFound 对象不存在，驱动返回的错误可以通过 errors.Is 判断
+	ErrObjectNotFound = errors.New("upload: object not found")
+	// ErrListUploadsUnsupported 驱动无法列举未完成的分片上传，Janitor 会在报告中记录该错误
+	ErrListUploadsUnsupported = errors.New("upload: listing multipart uploads is not supported by this driver")
+	// ErrInvalidPartOrder 完成分片上传时分片号重复或未按升序排列
+	ErrInvalidPartOrder = errors.New("upload: part numbers must be unique and in ascending order")
+)
+
 // Part 定义上传分片
 type Part struct {
 	ETag       string
 	PartNumber int
 }
 
+// CompleteResult 完成分片上传的结果
+type CompleteResult struct {
+	ObjectKey string
+	Size      int64
+	ETag      string
+	MD5       string // 整个对象的 MD5，十六进制，存储不提供时为空
+	CRC64     uint64 // 整个对象的 CRC64 ECMA，存储不提供时为 0
+}
+
+// PresignOptions 预签名地址的参数
+type PresignOptions struct {
+	Expires     time.Duration // 有效期，为 0 时使用 DefaultPresignExpires
+	ContentType string        // 上传时必须携带的 Content-Type，为空时不限制
+	MaxSize     int64         // 上传的最大字节数，为 0 时不限制，驱动无法限制时返回 ErrPresignMaxSize
+}
+
+func (o PresignOptions) expires() time.Duration {
+	if o.Expires <= 0 {
+		return DefaultPresignExpires
+	}
+	return o.Expires
+}
+
+// Range 读取对象的字节范围
+type Range struct {
+	Offset int64 // 起始位置
+	Length int64 // 读取长度，小于等于 0 时读取到对象末尾
+}
+
+// header 返回 HTTP Range 请求头的值
+func (r *Range) header() string {
+	if r.Length <= 0 {
+		return fmt.Sprintf("bytes=%d-", r.Offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", r.Offset, r.Offset+r.Length-1)
+}
+
+// ObjectInfo 对象元数据
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"contentType,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"lastModified"`
+	IsDir        bool      `json:"isDir,omitempty"` // ListObjects 按 Delimiter 折叠的目录，Key 以 Delimiter 结尾，其余字段为空
+}
+
 // Uploader 接口支持多种存储类型
 type Uploader interface {
-	InitiateMultipartUpload(ext string) (uploadID string, objectKey string, err error)
-	UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64) (string, error)
-	CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (string, error)
+	InitiateMultipartUpload(ext string, opts ...MultipartOption) (uploadID string, objectKey string, err error)
+	// UploadPart 上传分片并返回 ETag，opts 提供校验值时内容不一致返回 ErrChecksumMismatch，
+	// 分片写入后才能校验的驱动会同时取消上传，错误也匹配 ErrSessionNotFound
+	UploadPart(objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error)
+	// CompleteMultipartUpload 校验并合并分片，返回对象的大小与整体校验值
+	CompleteMultipartUpload(objectKey, uploadID string, parts []Part) (*CompleteResult, error)
+	// ListParts 列出已上传的分片，用于断点续传
+	ListParts(objectKey, uploadID string) ([]PartInfo, error)
+	// AbortMultipartUpload 取消分片上传并清理已上传的分片
+	AbortMultipartUpload(objectKey, uploadID string) error
+	// ListMultipartUploads 列出所有未完成的分片上传，用于清理废弃的上传，驱动不支持时返回 ErrListUploadsUnsupported
+	ListMultipartUploads() ([]MultipartUpload, error)
 	UploadFile(objectKey string, reader io.Reader) (string, error)
 	CopyFolder(srcFolder, destFolder string) error
+	// CopyFile 复制单个对象，目标已存在时覆盖，源对象不存在时返回 ErrObjectNotFound
+	CopyFile(srcKey, destKey string) error
 	DeleteFolder(folderPath string, exclude ...string) error
 	ListFiles(directory string, suffixFilters ...string) ([]string, error)
+	// ListObjects 分页列出对象及其元数据，遍历所有对象可以使用 Objects
+	ListObjects(ctx context.Context, opts ListOptions) (*ObjectPage, error)
 	DeleteFile(objectKey string) error
+	// PresignPut 生成客户端直接上传对象的 PUT 地址
+	PresignPut(objectKey string, opts PresignOptions) (string, error)
+	// PresignGet 生成客户端直接下载对象的 GET 地址，只使用 opts.Expires
+	PresignGet(objectKey string, opts PresignOptions) (string, error)
+	// Open 读取对象内容，r 为 nil 时读取整个对象，调用方负责关闭
+	Open(objectKey string, r *Range) (io.ReadCloser, error)
+	// Download 将对象内容保存到本地文件，r 为 nil 时下载整个对象
+	Download(objectKey, filePath string, r *Range) error
+	// Stat 获取对象元数据，对象不存在时返回 ErrObjectNotFound
+	Stat(objectKey string) (*ObjectInfo, error)
+	// Exists 判断对象是否存在
+	Exists(objectKey string) (bool, error)
+	// URL 生成对象的访问地址，支持 CDN 域名、私有存储桶签名与图片处理参数
+	URL(objectKey string, opts ...URLOption) (string, error)
+
+	// 以下为支持 context 的版本，ctx 取消时中止传输、合并与分页遍历，不带 Ctx 的方法使用 context.Background()
+	// 生成地址不涉及网络请求，PresignPut、PresignGet 与 URL 没有 Ctx 版本
+	InitiateMultipartUploadCtx(ctx context.Context, ext string, opts ...MultipartOption) (uploadID string, objectKey string, err error)
+	UploadPartCtx(ctx context.Context, objectKey, uploadID string, partNumber int, reader io.Reader, partSize int64, opts ...PartOption) (string, error)
+	CompleteMultipartUploadCtx(ctx context.Context, objectKey, uploadID string, parts []Part) (*CompleteResult, error)
+	ListPartsCtx(ctx context.Context, objectKey, uploadID string) ([]PartInfo, error)
+	AbortMultipartUploadCtx(ctx context.Context, objectKey, uploadID string) error
+	ListMultipartUploadsCtx(ctx context.Context) ([]MultipartUpload, error)
+	UploadFileCtx(ctx context.Context, objectKey string, reader io.Reader) (string, error)
+	CopyFolderCtx(ctx context.Context, srcFolder, destFolder string) error
+	CopyFileCtx(ctx context.Context, srcKey, destKey string) error
+	DeleteFolderCtx(ctx context.Context, folderPath string, exclude ...string) error
+	ListFilesCtx(ctx context.Context, directory string, suffixFilters ...string) ([]string, error)
+	DeleteFileCtx(ctx context.Context, objectKey string) error
+	OpenCtx(ctx context.Context, objectKey string, r *Range) (io.ReadCloser, error)
+	DownloadCtx(ctx context.Context, objectKey, filePath string, r *Range) error
+	StatCtx(ctx context.Context, objectKey string) (*ObjectInfo, error)
+	ExistsCtx(ctx context.Context, objectKey string) (bool, error)
+}
+
+// Factory 根据存储配置创建上传器
+type Factory func(cfg config.StorageConf) (Uploader, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Factory)
+)
+
+// Register 注册存储驱动，通常在驱动所在包的 init 中调用，名称重复或 factory 为空时 panic
+func Register(name string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if factory == nil {
+		panic("upload: Register factory is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("upload: Register called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
+
+// Drivers 返回已注册的驱动名称
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // NewUploader 根据传入的配置信息返回适当的存储实例
-func NewUploader() (Uploader, error) {
-	cfg := config.GlobalStorage
-	switch cfg.Driver {
-	case "local":
-		return NewLocalUploader(cfg.Local.Directory), nil
-	case "oss":
-		return NewOssUploader(cfg)
-	default:
+func NewUploader(cfg config.StorageConf) (Uploader, error) {
+	driversMu.RLock()
+	factory, ok := drivers[cfg.Driver]
+	driversMu.RUnlock()
+
+	if !ok {
 		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Driver)
 	}
+	return factory(cfg)
+}
+
+// NewDefaultUploader 根据全局配置 config.GlobalStorage 返回存储实例
+func NewDefaultUploader() (Uploader, error) {
+	return NewUploader(config.GlobalStorage)
+}
+
+// saveFile 将 reader 的内容写入本地文件，写入失败或 ctx 取消时保留原文件
+func saveFile(ctx context.Context, filePath string, reader io.Reader) error {
+	file, err := createAtomicFile(filePath, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(file, newCtxReader(ctx, reader)); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return file.Commit()
+}
+
+// atomicFile 写入目标文件所在目录下的临时文件，Commit 时 fsync 后重命名为目标文件
+// 进程崩溃或写入失败时目标文件保持原样，不会出现截断的文件
+type atomicFile struct {
+	*os.File
+	path      string
+	committed bool
+}
+
+// createAtomicFile 创建 path 对应的临时文件，调用方必须 Close，未 Commit 时 Close 会删除临时文件
+func createAtomicFile(path string, perm os.FileMode) (*atomicFile, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	if err = file.Chmod(perm); err != nil {
+		file.Close()
+		_ = os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return &atomicFile{File: file, path: path}, nil
+}
+
+// Commit 将临时文件落盘并替换目标文件
+func (f *atomicFile) Commit() error {
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err := f.File.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+	f.committed = true
+	syncDir(filepath.Dir(f.path))
+	return nil
+}
+
+// Close 未 Commit 时关闭并删除临时文件
+func (f *atomicFile) Close() error {
+	if f.committed {
+		return nil
+	}
+	_ = f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// isAtomicTemp 判断文件名是否为 atomicFile 写入中的临时文件
+func isAtomicTemp(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, ".tmp-")
+}
+
+// syncDir 将目录项落盘，确保重命名在崩溃后仍然生效，部分平台不支持同步目录，忽略错误
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
+
+// ctxReader 每次读取前检查 ctx，使 io.Copy 等循环在 ctx 取消后尽快返回
+type ctxReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func newCtxReader(ctx context.Context, reader io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return reader
+	}
+	return &ctxReader{ctx: ctx, reader: reader}
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// isExcluded 判断对象是否位于 folderPath 下被排除的子路径中
+func isExcluded(key, folderPath string, exclude []string) bool {
+	for _, excluded := range exclude {
+		if strings.HasPrefix(key, path.Join(folderPath, excluded)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSuffix 未指定后缀时返回 true，否则判断 key 是否以任一后缀结尾
+func hasSuffix(key string, suffixFilters []string) bool {
+	if len(suffixFilters) == 0 {
+		return true
+	}
+	for _, suffix := range suffixFilters {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
 }
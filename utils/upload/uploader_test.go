@@ -0,0 +1,38 @@
+package upload
+
+import (
+	"testing"
+
+	"github.com/zhanghaidi/zero-common/config"
+)
+
+type fakeUploader struct {
+	Uploader
+	cfg config.StorageConf
+}
+
+func TestRegister(t *testing.T) {
+	Register("fake", func(cfg config.StorageConf) (Uploader, error) {
+		return &fakeUploader{cfg: cfg}, nil
+	})
+
+	cfg := config.StorageConf{Driver: "fake", Extra: map[string]string{"region": "cn"}}
+	u, err := NewUploader(cfg)
+	if err != nil {
+		t.Fatalf("NewUploader() error = %v", err)
+	}
+	if f, ok := u.(*fakeUploader); !ok || f.cfg.Extra["region"] != "cn" {
+		t.Errorf("NewUploader() = %#v", u)
+	}
+
+	if _, err = NewUploader(config.StorageConf{Driver: "missing"}); err == nil {
+		t.Error("NewUploader() with unknown driver should fail")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() twice should panic")
+		}
+	}()
+	Register("fake", func(cfg config.StorageConf) (Uploader, error) { return nil, nil })
+}
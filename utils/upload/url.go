@@ -0,0 +1,86 @@
+package upload
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrProcessUnsupported 驱动不支持图片处理参数
+var ErrProcessUnsupported = errors.New("upload: image process is not supported by this driver")
+
+// URLOption URL 的可选参数
+type URLOption func(*urlOptions)
+
+type urlOptions struct {
+	domain  string        // 替换地址中协议与主机的域名
+	signed  bool          // 是否生成带签名的地址
+	expires time.Duration // 签名地址的有效期
+	process string        // 图片处理参数
+}
+
+// WithDomain 使用指定域名替换地址中的协议与主机，优先于配置中的 CdnURL
+func WithDomain(domain string) URLOption {
+	return func(o *urlOptions) {
+		o.domain = domain
+	}
+}
+
+// WithExpires 生成带签名的地址，用于访问私有存储桶，expires 为 0 时使用 DefaultPresignExpires
+func WithExpires(expires time.Duration) URLOption {
+	return func(o *urlOptions) {
+		o.signed = true
+		o.expires = expires
+	}
+}
+
+// WithProcess 附加图片处理参数，如 OSS 的 image/resize,w_200
+func WithProcess(process string) URLOption {
+	return func(o *urlOptions) {
+		o.process = process
+	}
+}
+
+// urlResolver 保存驱动生成访问地址时共用的配置
+type urlResolver struct {
+	cdnURL  string
+	private bool
+}
+
+// options 合并配置与调用参数，私有存储桶默认生成签名地址
+func (r urlResolver) options(opts []URLOption) urlOptions {
+	o := urlOptions{domain: r.cdnURL, signed: r.private}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.expires <= 0 {
+		o.expires = DefaultPresignExpires
+	}
+	return o
+}
+
+// joinURL 拼接访问地址与对象键，对象键按路径编码
+func joinURL(base, objectKey string) string {
+	return strings.TrimSuffix(base, "/") + "/" + uriEncode(objectKey, false)
+}
+
+// withDomain 使用 domain 替换 rawURL 的协议与主机，domain 未指定协议时使用 https
+func withDomain(rawURL, domain string) (string, error) {
+	if domain == "" {
+		return rawURL, nil
+	}
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	d, err := url.Parse(domain)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme, u.Host = d.Scheme, d.Host
+	return u.String(), nil
+}